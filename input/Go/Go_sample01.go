@@ -8,10 +8,14 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -26,9 +30,22 @@ type Command interface {
 }
 
 type FileAnalyzerCommand struct {
-	recursive bool
-	pattern   string
-	output    string
+	recursive     bool
+	pattern       string
+	output        string
+	watch         bool
+	watchInterval time.Duration
+	color         *colorizer
+}
+
+const (
+	watchDebounce    = 500 * time.Millisecond
+	progressInterval = 250 * time.Millisecond
+)
+
+type fileState struct {
+	size    int64
+	modTime time.Time
 }
 
 func (f *FileAnalyzerCommand) Execute(args []string) error {
@@ -42,7 +59,110 @@ func (f *FileAnalyzerCommand) Execute(args []string) error {
 		return fmt.Errorf("failed to analyze directory: %w", err)
 	}
 	
-	return f.outputResults(analysis)
+	if err := f.outputResults(analysis); err != nil {
+		return err
+	}
+	
+	if f.watch {
+		return f.watchDirectory(dirPath)
+	}
+	return nil
+}
+
+func (f *FileAnalyzerCommand) watchDirectory(dirPath string) error {
+	interval := f.watchInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	last, err := f.snapshot(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot directory: %w", err)
+	}
+	
+	fmt.Fprintf(os.Stderr, "Watching %s for changes (Ctrl-C to stop)...\n", dirPath)
+	
+	pending := false
+	var changedAt time.Time
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+			current, err := f.snapshot(dirPath)
+			if err != nil {
+				return fmt.Errorf("failed to snapshot directory: %w", err)
+			}
+			
+			if !sameSnapshot(last, current) {
+				last = current
+				pending = true
+				changedAt = time.Now()
+				continue
+			}
+			
+			if !pending || time.Since(changedAt) < watchDebounce {
+				continue
+			}
+			pending = false
+			
+			analysis, err := f.analyzeDirectory(dirPath)
+			if err != nil {
+				return fmt.Errorf("failed to analyze directory: %w", err)
+			}
+			
+			fmt.Println()
+			if err := f.outputResults(analysis); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (f *FileAnalyzerCommand) snapshot(dirPath string) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		
+		if d.IsDir() {
+			if !f.recursive && path != dirPath {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		states[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	
+	return states, err
+}
+
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		other, exists := b[path]
+		if !exists || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
 }
 
 func (f *FileAnalyzerCommand) Help() string {
@@ -51,7 +171,10 @@ Usage: analyze [options] <directory>
 Options:
   -r, --recursive  Analyze subdirectories recursively
   -p, --pattern    File pattern to match (glob)
-  -o, --output     Output format (text, json)`
+  -o, --output     Output format (text, json)
+  --watch          Re-analyze whenever files change
+  --watch-interval Polling interval for --watch
+  --no-color       Disable colored output`
 }
 
 type FileAnalysis struct {
@@ -88,6 +211,12 @@ func (f *FileAnalyzerCommand) analyzeDirectory(dirPath string) (*FileAnalysis, e
 		walkFunc = f.walkDirSingle(analysis)
 	}
 	
+	if f.output != "json" && isTerminal(os.Stderr) {
+		var stopProgress func()
+		walkFunc, stopProgress = f.withProgress(analysis, walkFunc)
+		defer stopProgress()
+	}
+	
 	err := filepath.WalkDir(dirPath, walkFunc)
 	if err != nil {
 		return nil, err
@@ -97,6 +226,42 @@ func (f *FileAnalyzerCommand) analyzeDirectory(dirPath string) (*FileAnalysis, e
 	return analysis, nil
 }
 
+func (f *FileAnalyzerCommand) withProgress(analysis *FileAnalysis, walkFunc fs.WalkDirFunc) (fs.WalkDirFunc, func()) {
+	var files, bytes int64
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	
+	go func() {
+		defer close(finished)
+		
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Fprintf(os.Stderr, "\rScanned %d files (%s)...", atomic.LoadInt64(&files), formatBytes(atomic.LoadInt64(&bytes)))
+			case <-done:
+				fmt.Fprint(os.Stderr, "\r\033[K")
+				return
+			}
+		}
+	}()
+	
+	tracked := func(path string, d fs.DirEntry, err error) error {
+		walkErr := walkFunc(path, d, err)
+		atomic.StoreInt64(&files, int64(analysis.TotalFiles))
+		atomic.StoreInt64(&bytes, analysis.TotalSize)
+		return walkErr
+	}
+	
+	stop := func() {
+		close(done)
+		<-finished
+	}
+	return tracked, stop
+}
+
 func (f *FileAnalyzerCommand) walkDirRecursive(analysis *FileAnalysis) fs.WalkDirFunc {
 	return func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -168,9 +333,11 @@ func (f *FileAnalyzerCommand) processFile(analysis *FileAnalysis, path string, d
 }
 
 func (f *FileAnalyzerCommand) calculateSummary(analysis *FileAnalysis) {
+	var averageSize int64
 	if analysis.TotalFiles > 0 {
-		analysis.Summary["average_file_size"] = analysis.TotalSize / int64(analysis.TotalFiles)
+		averageSize = analysis.TotalSize / int64(analysis.TotalFiles)
 	}
+	analysis.Summary["average_file_size"] = averageSize
 	
 	var mostCommonType string
 	var maxCount int
@@ -196,24 +363,26 @@ func (f *FileAnalyzerCommand) outputResults(analysis *FileAnalysis) error {
 }
 
 func (f *FileAnalyzerCommand) outputText(analysis *FileAnalysis) error {
-	fmt.Printf("Directory Analysis: %s\n", analysis.Directory)
+	c := f.color
+	fmt.Printf("%s\n", c.header("Directory Analysis: "+analysis.Directory))
 	fmt.Printf("Analyzed at: %s\n", analysis.AnalyzedAt.Format(time.RFC3339))
 	fmt.Printf("==========================================\n")
-	fmt.Printf("Total files: %d\n", analysis.TotalFiles)
-	fmt.Printf("Total size: %s\n", formatBytes(analysis.TotalSize))
-	fmt.Printf("Average size: %s\n", formatBytes(analysis.Summary["average_file_size"].(int64)))
+	fmt.Printf("Total files: %s\n", c.count(analysis.TotalFiles))
+	fmt.Printf("Total size: %s\n", c.size(formatBytes(analysis.TotalSize)))
+	averageSize, _ := analysis.Summary["average_file_size"].(int64)
+	fmt.Printf("Average size: %s\n", c.size(formatBytes(averageSize)))
 	
-	fmt.Printf("\nFile Types:\n")
+	fmt.Printf("\n%s\n", c.header("File Types:"))
 	for ext, count := range analysis.FileTypes {
-		fmt.Printf("  %s: %d files\n", ext, count)
+		fmt.Printf("  %s: %s files\n", ext, c.count(count))
 	}
 	
-	fmt.Printf("\nLargest Files:\n")
+	fmt.Printf("\n%s\n", c.header("Largest Files:"))
 	for i, file := range analysis.LargestFiles {
 		if i >= 5 {
 			break
 		}
-		fmt.Printf("  %s (%s)\n", file.Path, formatBytes(file.Size))
+		fmt.Printf("  %s (%s)\n", file.Path, c.size(formatBytes(file.Size)))
 	}
 	
 	return nil
@@ -223,6 +392,7 @@ type TextProcessorCommand struct {
 	operation string
 	ignoreCase bool
 	output    string
+	color     *colorizer
 }
 
 func (t *TextProcessorCommand) Execute(args []string) error {
@@ -240,7 +410,8 @@ Usage: text [options] <file>
 Options:
   --operation  Operation to perform (count, search, replace)
   --ignore-case Ignore case for operations
-  --output     Output file (default: stdout)`
+  --output     Output file (default: stdout)
+  --no-color   Disable colored output`
 }
 
 func (t *TextProcessorCommand) processTextFile(filePath string) error {
@@ -278,9 +449,9 @@ func (t *TextProcessorCommand) countLines(file *os.File) error {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 	
-	fmt.Printf("Lines: %d\n", lineCount)
-	fmt.Printf("Words: %d\n", wordCount)
-	fmt.Printf("Characters: %d\n", charCount)
+	fmt.Printf("Lines: %s\n", t.color.count(lineCount))
+	fmt.Printf("Words: %s\n", t.color.count(wordCount))
+	fmt.Printf("Characters: %s\n", t.color.count(charCount))
 	
 	return nil
 }
@@ -292,7 +463,7 @@ func (t *TextProcessorCommand) searchText(file *os.File) error {
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.Contains(strings.ToLower(line), "error") {
-			fmt.Printf("Line %d: %s\n", lineNumber, line)
+			fmt.Printf("%s: %s\n", t.color.header(fmt.Sprintf("Line %d", lineNumber)), line)
 		}
 		lineNumber++
 	}
@@ -334,17 +505,144 @@ func (t *TextProcessorCommand) analyzeText(file *os.File) error {
 		return words[i].count > words[j].count
 	})
 	
-	fmt.Printf("Top 10 words:\n")
+	fmt.Printf("%s\n", t.color.header("Top 10 words:"))
 	for i, wc := range words {
 		if i >= 10 {
 			break
 		}
-		fmt.Printf("  %s: %d\n", wc.word, wc.count)
+		fmt.Printf("  %s: %s\n", wc.word, t.color.count(wc.count))
 	}
 	
 	return nil
 }
 
+type TreeCommand struct {
+	recursive  *bool
+	pattern    *string
+	maxDepth   int
+	jsonOutput bool
+}
+
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"`
+	Size     int64       `json:"size"`
+	IsDir    bool        `json:"is_dir"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+func (t *TreeCommand) Execute(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tree <directory>")
+	}
+	
+	root, err := t.buildTree(args[0], 0)
+	if err != nil {
+		return fmt.Errorf("failed to build tree: %w", err)
+	}
+	
+	if t.jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(root)
+	}
+	
+	fmt.Printf("%s (%s)\n", root.Path, formatBytes(root.Size))
+	t.printChildren(root, "")
+	return nil
+}
+
+func (t *TreeCommand) Help() string {
+	return `tree - Print a directory tree with file sizes
+Usage: tree [options] <directory>
+Options:
+  -r, --recursive  Descend into subdirectories
+  -p, --pattern    Only show files matching pattern (glob)
+  --depth          Maximum depth when recursive (0 = unlimited)
+  --json           Emit a nested JSON structure`
+}
+
+func (t *TreeCommand) buildTree(path string, depth int) (*TreeNode, error) {
+	stat := os.Stat
+	if depth > 0 {
+		// Below the root, list symlinks as entries instead of following
+		// them, so a link back up the tree cannot recurse forever.
+		stat = os.Lstat
+	}
+	info, err := stat(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	node := &TreeNode{
+		Name:  info.Name(),
+		Path:  path,
+		IsDir: info.IsDir(),
+	}
+	
+	if !info.IsDir() {
+		node.Size = info.Size()
+		return node, nil
+	}
+	
+	if depth > 0 && !t.descend(depth) {
+		return node, nil
+	}
+	
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, entry := range entries {
+		if !entry.IsDir() && *t.pattern != "" {
+			matched, err := filepath.Match(*t.pattern, entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		
+		child, err := t.buildTree(filepath.Join(path, entry.Name()), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		
+		node.Size += child.Size
+		node.Children = append(node.Children, child)
+	}
+	
+	return node, nil
+}
+
+func (t *TreeCommand) descend(depth int) bool {
+	if !*t.recursive {
+		return false
+	}
+	return t.maxDepth <= 0 || depth < t.maxDepth
+}
+
+func (t *TreeCommand) printChildren(node *TreeNode, prefix string) {
+	for i, child := range node.Children {
+		connector, nextPrefix := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			connector, nextPrefix = "└── ", "    "
+		}
+		
+		name := child.Name
+		if child.IsDir {
+			name += "/"
+		}
+		fmt.Printf("%s%s%s (%s)\n", prefix, connector, name, formatBytes(child.Size))
+		
+		if child.IsDir {
+			t.printChildren(child, prefix+nextPrefix)
+		}
+	}
+}
+
 type SystemInfoCommand struct{}
 
 func (s *SystemInfoCommand) Execute(args []string) error {
@@ -367,6 +665,49 @@ func (s *SystemInfoCommand) Help() string {
 Usage: sysinfo`
 }
 
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+type colorizer struct {
+	disabled bool
+}
+
+func (c *colorizer) enabled() bool {
+	return c != nil && !c.disabled && isTerminal(os.Stdout)
+}
+
+func (c *colorizer) paint(code, text string) string {
+	if !c.enabled() {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+func (c *colorizer) header(text string) string {
+	return c.paint(ansiBold+ansiCyan, text)
+}
+
+func (c *colorizer) size(text string) string {
+	return c.paint(ansiGreen, text)
+}
+
+func (c *colorizer) count(n int) string {
+	return c.paint(ansiYellow, strconv.Itoa(n))
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func formatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -394,22 +735,50 @@ func NewCLIApp() *CLIApp {
 		flags:    flag.NewFlagSet("cli-tool", flag.ExitOnError),
 	}
 	
-	fileAnalyzer := &FileAnalyzerCommand{}
+	color := &colorizer{}
+	app.flags.BoolVar(&color.disabled, "no-color", false, "Disable colored output")
+	
+	fileAnalyzer := &FileAnalyzerCommand{color: color}
 	app.flags.BoolVar(&fileAnalyzer.recursive, "r", false, "Recursive analysis")
 	app.flags.StringVar(&fileAnalyzer.pattern, "p", "", "File pattern")
 	app.flags.StringVar(&fileAnalyzer.output, "o", "text", "Output format")
+	app.flags.BoolVar(&fileAnalyzer.watch, "watch", false, "Watch for changes")
+	app.flags.DurationVar(&fileAnalyzer.watchInterval, "watch-interval", time.Second, "Watch polling interval")
 	app.commands["analyze"] = fileAnalyzer
 	
-	textProcessor := &TextProcessorCommand{}
+	textProcessor := &TextProcessorCommand{color: color}
 	app.flags.StringVar(&textProcessor.operation, "operation", "analyze", "Text operation")
 	app.flags.BoolVar(&textProcessor.ignoreCase, "ignore-case", false, "Ignore case")
 	app.commands["text"] = textProcessor
 	
+	tree := &TreeCommand{
+		recursive: &fileAnalyzer.recursive,
+		pattern:   &fileAnalyzer.pattern,
+	}
+	app.flags.IntVar(&tree.maxDepth, "depth", 0, "Maximum tree depth")
+	app.flags.BoolVar(&tree.jsonOutput, "json", false, "JSON tree output")
+	app.commands["tree"] = tree
+	
 	app.commands["sysinfo"] = &SystemInfoCommand{}
 	
 	return app
 }
 
+func (app *CLIApp) Register(name string, cmd Command) error {
+	if name == "" {
+		return fmt.Errorf("command name cannot be empty")
+	}
+	if cmd == nil {
+		return fmt.Errorf("command %s is nil", name)
+	}
+	if _, exists := app.commands[name]; exists {
+		return fmt.Errorf("command already registered: %s", name)
+	}
+	
+	app.commands[name] = cmd
+	return nil
+}
+
 func (app *CLIApp) Run(args []string) error {
 	if len(args) < 2 {
 		app.showHelp()
@@ -433,7 +802,14 @@ func (app *CLIApp) showHelp() {
 	fmt.Printf("Usage: %s <command> [options] [args]\n\n", os.Args[0])
 	fmt.Printf("Available commands:\n")
 	
-	for name, command := range app.commands {
+	names := make([]string, 0, len(app.commands))
+	for name := range app.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	for _, name := range names {
+		command := app.commands[name]
 		fmt.Printf("  %s\n", name)
 		helpLines := strings.Split(command.Help(), "\n")
 		for _, line := range helpLines[1:] {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fnErr := fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read stdout: %v", err)
+	}
+	if fnErr != nil {
+		t.Fatalf("unexpected error: %v", fnErr)
+	}
+	return string(out)
+}
+
+func TestAnalyzeEmptyDirectory(t *testing.T) {
+	for _, recursive := range []bool{false, true} {
+		f := &FileAnalyzerCommand{recursive: recursive, output: "json"}
+
+		analysis, err := f.analyzeDirectory(t.TempDir())
+		if err != nil {
+			t.Fatalf("analyzeDirectory(recursive=%v): %v", recursive, err)
+		}
+
+		if analysis.TotalFiles != 0 || analysis.TotalSize != 0 || len(analysis.LargestFiles) != 0 {
+			t.Fatalf("empty directory analysis = %+v, want no files", analysis)
+		}
+		if got := analysis.Summary["average_file_size"]; got != int64(0) {
+			t.Errorf("average_file_size = %v, want 0", got)
+		}
+		if got := analysis.Summary["most_common_type"]; got != "" {
+			t.Errorf("most_common_type = %q, want empty", got)
+		}
+		if got := analysis.Summary["most_common_count"]; got != 0 {
+			t.Errorf("most_common_count = %v, want 0", got)
+		}
+
+		out := captureStdout(t, func() error { return f.outputText(analysis) })
+		for _, want := range []string{"Total files: 0\n", "Total size: 0 B\n", "Average size: 0 B\n"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("outputText missing %q in:\n%s", want, out)
+			}
+		}
+	}
+}
+
+func TestBuildTreeDoesNotFollowSymlinkLoops(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "file.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(sub, "loop")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	recursive, pattern := true, ""
+	tc := &TreeCommand{recursive: &recursive, pattern: &pattern}
+	node, err := tc.buildTree(root, 0)
+	if err != nil {
+		t.Fatalf("buildTree: %v", err)
+	}
+
+	if len(node.Children) != 1 || len(node.Children[0].Children) != 2 {
+		t.Fatalf("unexpected tree shape: %+v", node)
+	}
+	for _, child := range node.Children[0].Children {
+		if child.Name == "loop" && (child.IsDir || len(child.Children) != 0) {
+			t.Fatalf("symlink %s was followed: %+v", child.Path, child)
+		}
+	}
+}
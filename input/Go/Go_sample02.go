@@ -1,13 +1,19 @@
 package main
 
 import (
+	"container/heap"
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"math/rand"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
+	
+	"go-security-scan/ringlog"
 )
 
 type Task struct {
@@ -15,6 +21,7 @@ type Task struct {
 	Data     string        `json:"data"`
 	Priority int           `json:"priority"`
 	Duration time.Duration `json:"duration"`
+	ctx      context.Context
 }
 
 type Result struct {
@@ -23,14 +30,98 @@ type Result struct {
 	ProcessedAt time.Time   `json:"processed_at"`
 	Duration  time.Duration `json:"duration"`
 	WorkerID  int           `json:"worker_id"`
+	Error     string        `json:"error,omitempty"`
 }
 
 type JobStats struct {
-	TotalTasks     int           `json:"total_tasks"`
-	CompletedTasks int           `json:"completed_tasks"`
-	FailedTasks    int           `json:"failed_tasks"`
-	TotalDuration  time.Duration `json:"total_duration"`
-	AvgDuration    time.Duration `json:"avg_duration"`
+	TotalTasks      int           `json:"total_tasks"`
+	CompletedTasks  int           `json:"completed_tasks"`
+	FailedTasks     int           `json:"failed_tasks"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	AvgDuration     time.Duration `json:"avg_duration"`
+	P50Duration     time.Duration `json:"p50_duration"`
+	P95Duration     time.Duration `json:"p95_duration"`
+	P99Duration     time.Duration `json:"p99_duration"`
+	WorkerCompleted map[int]int   `json:"worker_completed"`
+	DroppedResults  int           `json:"dropped_results"`
+	DroppedBroadcasts int         `json:"dropped_broadcasts"`
+	RejectedTasks   int           `json:"rejected_tasks"`
+	TimedOutTasks   int           `json:"timed_out_tasks"`
+}
+
+type ResultPolicy int
+
+const (
+	ResultPolicyBlock ResultPolicy = iota
+	ResultPolicyDropOldest
+	ResultPolicyRejectNew
+)
+
+var ErrResultQueueFull = errors.New("result queue is full")
+
+var ErrTaskTimeout = errors.New("task timed out")
+
+type scheduledTask struct {
+	task  Task
+	ctx   context.Context
+	runAt time.Time
+}
+
+type scheduleQueue []*scheduledTask
+
+func (sq scheduleQueue) Len() int           { return len(sq) }
+func (sq scheduleQueue) Less(i, j int) bool { return sq[i].runAt.Before(sq[j].runAt) }
+func (sq scheduleQueue) Swap(i, j int)      { sq[i], sq[j] = sq[j], sq[i] }
+
+func (sq *scheduleQueue) Push(x interface{}) {
+	*sq = append(*sq, x.(*scheduledTask))
+}
+
+func (sq *scheduleQueue) Pop() interface{} {
+	old := *sq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*sq = old[:n-1]
+	return item
+}
+
+const latencySampleSize = 1024
+
+type latencyRing struct {
+	samples *ringlog.Log[time.Duration]
+}
+
+func newLatencyRing(size int) *latencyRing {
+	return &latencyRing{samples: ringlog.New[time.Duration](size)}
+}
+
+func (lr *latencyRing) Add(d time.Duration) {
+	lr.samples.Append(d)
+}
+
+func (lr *latencyRing) Percentiles(ps ...float64) []time.Duration {
+	n := lr.samples.Len()
+	
+	results := make([]time.Duration, len(ps))
+	if n == 0 {
+		return results
+	}
+	
+	sorted := lr.samples.Entries()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	
+	for i, p := range ps {
+		idx := int(p*float64(n)+0.5) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= n {
+			idx = n - 1
+		}
+		results[i] = sorted[idx]
+	}
+	return results
 }
 
 type WorkerPool struct {
@@ -41,21 +132,58 @@ type WorkerPool struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	stats      *JobStats
+	latencies  *latencyRing
+	resultPolicy ResultPolicy
+	taskTimeout time.Duration
+	paused     bool
+	pauseCh    chan struct{}
+	resumeCh   chan struct{}
+	schedule   scheduleQueue
+	scheduleWake chan struct{}
+	schedCtx   context.Context
+	schedCancel context.CancelFunc
+	schedWg    sync.WaitGroup
 	mu         sync.Mutex
+	subMu      sync.Mutex
+	subscribers map[<-chan Result]chan Result
 }
 
+const subscriberBufferSize = 64
+
 func NewWorkerPool(numWorkers int, queueSize int) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
+	schedCtx, schedCancel := context.WithCancel(ctx)
 	return &WorkerPool{
 		numWorkers:  numWorkers,
 		taskQueue:   make(chan Task, queueSize),
 		resultQueue: make(chan Result, queueSize),
 		ctx:         ctx,
 		cancel:      cancel,
-		stats:       &JobStats{},
+		stats:       &JobStats{WorkerCompleted: make(map[int]int)},
+		latencies:   newLatencyRing(latencySampleSize),
+		pauseCh:     make(chan struct{}),
+		scheduleWake: make(chan struct{}, 1),
+		schedCtx:    schedCtx,
+		schedCancel: schedCancel,
+		subscribers: make(map[<-chan Result]chan Result),
 	}
 }
 
+func (wp *WorkerPool) SetResultPolicy(policy ResultPolicy) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.resultPolicy = policy
+}
+
+// SetTaskTimeout bounds how long a worker waits on a single task. A task that
+// overruns is recorded as failed with ErrTaskTimeout and its goroutine is
+// abandoned: its context is cancelled, but it keeps running until it returns.
+func (wp *WorkerPool) SetTaskTimeout(timeout time.Duration) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.taskTimeout = timeout
+}
+
 func (wp *WorkerPool) Start() {
 	log.Printf("Starting worker pool with %d workers", wp.numWorkers)
 	
@@ -65,21 +193,49 @@ func (wp *WorkerPool) Start() {
 	}
 	
 	go wp.collectResults()
+	wp.schedWg.Add(1)
+	go wp.runScheduler()
 }
 
 func (wp *WorkerPool) worker(id int) {
 	defer wp.wg.Done()
 	
 	for {
+		pauseCh, ok := wp.waitIfPaused()
+		if !ok {
+			return
+		}
+		
 		select {
-		case task := <-wp.taskQueue:
+		case <-pauseCh:
+			continue
+		
+		case task, ok := <-wp.taskQueue:
+			if !ok {
+				return
+			}
+			// Pause may have raced the receive; hold the task until Resume.
+			if _, ok := wp.waitIfPaused(); !ok {
+				return
+			}
+			
+			taskCtx := task.ctx
+			if taskCtx == nil {
+				taskCtx = wp.ctx
+			}
+			if err := taskCtx.Err(); err != nil {
+				log.Printf("Worker %d skipping task %d: %v", id, task.ID, err)
+				wp.mu.Lock()
+				wp.stats.FailedTasks++
+				wp.mu.Unlock()
+				continue
+			}
+			
 			start := time.Now()
-			result := wp.processTask(task, id)
+			result := wp.runTask(taskCtx, task, id)
 			result.Duration = time.Since(start)
 			
-			select {
-			case wp.resultQueue <- result:
-			case <-wp.ctx.Done():
+			if !wp.sendResult(result) {
 				return
 			}
 			
@@ -90,8 +246,144 @@ func (wp *WorkerPool) worker(id int) {
 	}
 }
 
-func (wp *WorkerPool) processTask(task Task, workerID int) Result {
-	time.Sleep(task.Duration)
+// Pause stops workers from picking up new tasks once they finish their
+// current one. Queued tasks stay in the queue until Resume is called.
+func (wp *WorkerPool) Pause() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	if wp.paused {
+		return
+	}
+	wp.paused = true
+	wp.resumeCh = make(chan struct{})
+	close(wp.pauseCh)
+}
+
+func (wp *WorkerPool) Resume() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	if !wp.paused {
+		return
+	}
+	wp.paused = false
+	close(wp.resumeCh)
+	wp.pauseCh = make(chan struct{})
+}
+
+func (wp *WorkerPool) IsPaused() bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.paused
+}
+
+// waitIfPaused blocks while the pool is paused and returns a channel that is
+// closed by the next Pause, so idle workers stop waiting on the task queue.
+func (wp *WorkerPool) waitIfPaused() (<-chan struct{}, bool) {
+	for {
+		wp.mu.Lock()
+		if !wp.paused {
+			pauseCh := wp.pauseCh
+			wp.mu.Unlock()
+			return pauseCh, true
+		}
+		gate := wp.resumeCh
+		wp.mu.Unlock()
+		
+		select {
+		case <-gate:
+		case <-wp.ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (wp *WorkerPool) sendResult(result Result) bool {
+	wp.mu.Lock()
+	policy := wp.resultPolicy
+	wp.mu.Unlock()
+	
+	if policy != ResultPolicyDropOldest {
+		select {
+		case wp.resultQueue <- result:
+			return true
+		case <-wp.ctx.Done():
+			return false
+		}
+	}
+	
+	for {
+		select {
+		case wp.resultQueue <- result:
+			return true
+		case <-wp.ctx.Done():
+			return false
+		default:
+		}
+		
+		select {
+		case dropped := <-wp.resultQueue:
+			wp.mu.Lock()
+			wp.stats.DroppedResults++
+			wp.mu.Unlock()
+			log.Printf("Result queue full, dropped result for task %d", dropped.TaskID)
+		default:
+		}
+	}
+}
+
+func (wp *WorkerPool) runTask(ctx context.Context, task Task, workerID int) Result {
+	wp.mu.Lock()
+	timeout := wp.taskTimeout
+	wp.mu.Unlock()
+	
+	if timeout <= 0 {
+		return wp.processTask(ctx, task, workerID)
+	}
+	
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
+	done := make(chan Result, 1)
+	go func() {
+		done <- wp.processTask(ctx, task, workerID)
+	}()
+	
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+		wp.mu.Lock()
+		wp.stats.TimedOutTasks++
+		wp.mu.Unlock()
+		
+		return Result{
+			TaskID:      task.ID,
+			ProcessedAt: time.Now(),
+			WorkerID:    workerID,
+			Error:       ErrTaskTimeout.Error(),
+		}
+	}
+}
+
+func (wp *WorkerPool) processTask(ctx context.Context, task Task, workerID int) Result {
+	timer := time.NewTimer(task.Duration)
+	defer timer.Stop()
+	
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return Result{
+			TaskID:      task.ID,
+			ProcessedAt: time.Now(),
+			WorkerID:    workerID,
+			Error:       ctx.Err().Error(),
+		}
+	}
 	
 	output := fmt.Sprintf("Processed task %d: %s (Worker %d)", task.ID, task.Data, workerID)
 	
@@ -103,16 +395,79 @@ func (wp *WorkerPool) processTask(task Task, workerID int) Result {
 	}
 }
 
+func (wp *WorkerPool) Subscribe() <-chan Result {
+	ch := make(chan Result, subscriberBufferSize)
+	
+	wp.subMu.Lock()
+	wp.subscribers[ch] = ch
+	wp.subMu.Unlock()
+	
+	return ch
+}
+
+func (wp *WorkerPool) Unsubscribe(sub <-chan Result) {
+	wp.subMu.Lock()
+	defer wp.subMu.Unlock()
+	
+	if ch, ok := wp.subscribers[sub]; ok {
+		delete(wp.subscribers, sub)
+		close(ch)
+	}
+}
+
+func (wp *WorkerPool) broadcast(result Result) {
+	wp.subMu.Lock()
+	defer wp.subMu.Unlock()
+	
+	dropped := 0
+	for _, ch := range wp.subscribers {
+		select {
+		case ch <- result:
+		default:
+			dropped++
+		}
+	}
+	
+	if dropped > 0 {
+		wp.mu.Lock()
+		wp.stats.DroppedBroadcasts += dropped
+		wp.mu.Unlock()
+	}
+}
+
+func (wp *WorkerPool) closeSubscribers() {
+	wp.subMu.Lock()
+	defer wp.subMu.Unlock()
+	
+	for sub, ch := range wp.subscribers {
+		delete(wp.subscribers, sub)
+		close(ch)
+	}
+}
+
 func (wp *WorkerPool) collectResults() {
 	for {
 		select {
 		case result := <-wp.resultQueue:
+			wp.broadcast(result)
+			
+			if result.Error != "" {
+				wp.mu.Lock()
+				wp.stats.FailedTasks++
+				wp.mu.Unlock()
+				
+				log.Printf("Task %d failed on worker %d: %s", result.TaskID, result.WorkerID, result.Error)
+				continue
+			}
+			
 			wp.mu.Lock()
 			wp.stats.CompletedTasks++
 			wp.stats.TotalDuration += result.Duration
 			if wp.stats.CompletedTasks > 0 {
 				wp.stats.AvgDuration = wp.stats.TotalDuration / time.Duration(wp.stats.CompletedTasks)
 			}
+			wp.stats.WorkerCompleted[result.WorkerID]++
+			wp.latencies.Add(result.Duration)
 			wp.mu.Unlock()
 			
 			log.Printf("Task %d completed by worker %d in %v", 
@@ -124,29 +479,162 @@ func (wp *WorkerPool) collectResults() {
 	}
 }
 
-func (wp *WorkerPool) SubmitTask(task Task) {
+func (wp *WorkerPool) SubmitTask(ctx context.Context, task Task) error {
+	return wp.submit(ctx, task, wp.ctx)
+}
+
+// submit queues a task, giving up when either the task's context or the
+// given lifecycle context is canceled.
+func (wp *WorkerPool) submit(ctx context.Context, task Task, lifecycle context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	task.ctx = ctx
+	
 	wp.mu.Lock()
+	if wp.resultPolicy == ResultPolicyRejectNew && len(wp.resultQueue) == cap(wp.resultQueue) {
+		wp.stats.RejectedTasks++
+		wp.mu.Unlock()
+		return ErrResultQueueFull
+	}
 	wp.stats.TotalTasks++
 	wp.mu.Unlock()
 	
 	select {
 	case wp.taskQueue <- task:
-	case <-wp.ctx.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-lifecycle.Done():
+		return lifecycle.Err()
+	}
+}
+
+func (wp *WorkerPool) SubmitAfter(ctx context.Context, task Task, delay time.Duration) error {
+	return wp.SubmitAt(ctx, task, time.Now().Add(delay))
+}
+
+func (wp *WorkerPool) SubmitAt(ctx context.Context, task Task, when time.Time) error {
+	if err := wp.schedCtx.Err(); err != nil {
+		return err
+	}
+	
+	wp.mu.Lock()
+	heap.Push(&wp.schedule, &scheduledTask{task: task, ctx: ctx, runAt: when})
+	wp.mu.Unlock()
+	
+	select {
+	case wp.scheduleWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (wp *WorkerPool) PendingScheduled() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return len(wp.schedule)
+}
+
+func (wp *WorkerPool) runScheduler() {
+	defer wp.schedWg.Done()
+	
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	
+	for {
+		wp.mu.Lock()
+		wait := time.Hour
+		if len(wp.schedule) > 0 {
+			wait = time.Until(wp.schedule[0].runAt)
+		}
+		wp.mu.Unlock()
+		
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		
+		select {
+		case <-timer.C:
+			for _, due := range wp.popDueTasks(time.Now()) {
+				if err := wp.submit(due.ctx, due.task, wp.schedCtx); err != nil {
+					log.Printf("Failed to submit scheduled task %d: %v", due.task.ID, err)
+				}
+			}
+		case <-wp.scheduleWake:
+		case <-wp.schedCtx.Done():
+			wp.mu.Lock()
+			dropped := len(wp.schedule)
+			wp.schedule = nil
+			wp.mu.Unlock()
+			
+			if dropped > 0 {
+				log.Printf("Scheduler stopped with %d pending tasks discarded", dropped)
+			}
+			return
+		}
 	}
 }
 
+func (wp *WorkerPool) popDueTasks(now time.Time) []*scheduledTask {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	var due []*scheduledTask
+	for len(wp.schedule) > 0 && !wp.schedule[0].runAt.After(now) {
+		due = append(due, heap.Pop(&wp.schedule).(*scheduledTask))
+	}
+	return due
+}
+
 func (wp *WorkerPool) Stop() {
 	log.Println("Stopping worker pool...")
+	wp.Resume()
+	wp.schedCancel()
+	wp.schedWg.Wait()
 	close(wp.taskQueue)
 	wp.wg.Wait()
 	wp.cancel()
 	close(wp.resultQueue)
+	wp.closeSubscribers()
 }
 
 func (wp *WorkerPool) GetStats() JobStats {
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
-	return *wp.stats
+	
+	stats := *wp.stats
+	stats.WorkerCompleted = make(map[int]int, len(wp.stats.WorkerCompleted))
+	for workerID, count := range wp.stats.WorkerCompleted {
+		stats.WorkerCompleted[workerID] = count
+	}
+	
+	percentiles := wp.latencies.Percentiles(0.50, 0.95, 0.99)
+	stats.P50Duration = percentiles[0]
+	stats.P95Duration = percentiles[1]
+	stats.P99Duration = percentiles[2]
+	return stats
+}
+
+func (wp *WorkerPool) SetStatsRetention(max int) {
+	if max <= 0 {
+		max = latencySampleSize
+	}
+	
+	wp.mu.Lock()
+	wp.latencies.samples.SetMax(max)
+	wp.mu.Unlock()
+}
+
+func (wp *WorkerPool) LatencySampleCount() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	return wp.latencies.samples.Len()
 }
 
 type Pipeline struct {
@@ -295,6 +783,38 @@ func FanOut(input <-chan Task, numWorkers int) []<-chan Task {
 	return outputs
 }
 
+// FanOutByKey routes every task with the same key to the same output, so
+// tasks for a key are delivered in input order to a single consumer. A
+// numWorkers below one is treated as one.
+func FanOutByKey(input <-chan Task, numWorkers int, keyFn func(Task) string) []<-chan Task {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	
+	channels := make([]chan Task, numWorkers)
+	outputs := make([]<-chan Task, numWorkers)
+	for i := range channels {
+		channels[i] = make(chan Task)
+		outputs[i] = channels[i]
+	}
+	
+	go func() {
+		defer func() {
+			for _, ch := range channels {
+				close(ch)
+			}
+		}()
+		
+		for task := range input {
+			h := fnv.New32a()
+			h.Write([]byte(keyFn(task)))
+			channels[h.Sum32()%uint32(numWorkers)] <- task
+		}
+	}()
+	
+	return outputs
+}
+
 func FanIn(inputs ...<-chan Result) <-chan Result {
 	output := make(chan Result)
 	var wg sync.WaitGroup
@@ -394,7 +914,9 @@ func demoWorkerPool() {
 			Priority: rand.Intn(5) + 1,
 			Duration: time.Duration(rand.Intn(500)+100) * time.Millisecond,
 		}
-		pool.SubmitTask(task)
+		if err := pool.SubmitTask(context.Background(), task); err != nil {
+			log.Printf("Failed to submit task %d: %v", task.ID, err)
+		}
 	}
 	
 	time.Sleep(3 * time.Second)
@@ -1,47 +1,232 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type Category struct {
-	ID          int
-	Name        string
-	Description string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID          int       `db:"id,key"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at,readonly"`
+	UpdatedAt   time.Time `db:"updated_at,updated"`
 }
 
 type Product struct {
-	ID          int
-	Name        string
-	Description string
-	Price       float64
-	Stock       int
-	CategoryID  int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	IsActive    bool
+	ID          int                    `db:"id,key"`
+	Name        string                 `db:"name"`
+	Description string                 `db:"description"`
+	Price       float64                `db:"price"`
+	Stock       int                    `db:"stock"`
+	CategoryID  int                    `db:"category_id"`
+	CreatedAt   time.Time              `db:"created_at,readonly"`
+	UpdatedAt   time.Time              `db:"updated_at,updated"`
+	IsActive    bool                   `db:"is_active"`
+	Metadata    map[string]interface{} `db:"metadata,json"`
 }
 
 type ProductWithCategory struct {
 	Product
-	CategoryName        string
-	CategoryDescription string
+	CategoryName        string `db:"category_name"`
+	CategoryDescription string `db:"category_description"`
 }
 
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type NopLogger struct{}
+
+func (NopLogger) Printf(format string, v ...interface{}) {}
+
+type CategoryFacet struct {
+	ID           int
+	Name         string
+	ProductCount int
+}
+
+type PriceBucket struct {
+	Min          float64
+	Max          float64
+	Unbounded    bool
+	ProductCount int
+}
+
+type Facets struct {
+	Categories   []CategoryFacet
+	PriceBuckets []PriceBucket
+}
+
+var priceBucketBounds = []float64{0, 25, 50, 100, 500}
+
+const (
+	maxOpenConns = 25
+	maxIdleConns = 5
+)
+
 type DatabaseManager struct {
-	db           *sql.DB
-	mu           sync.RWMutex
-	transactions map[string]*sql.Tx
-	migrations   []Migration
+	db                *sql.DB
+	mu                sync.RWMutex
+	transactions      map[string]*sql.Tx
+	migrations        []Migration
+	Logger            Logger
+	returningOnce     sync.Once
+	supportsReturning bool
+	migrated          bool
+	warmedUp          bool
+	slowQuery         time.Duration
+	categories        *Repository[Category]
+	products          *Repository[Product]
+	schemaMu          sync.Mutex
+	maintenanceStop   chan struct{}
+	maintenanceDone   chan struct{}
+	queryCache        *queryCache
+}
+
+type QueryCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+type queryCacheEntry struct {
+	products []*ProductWithCategory
+	expires  time.Time
+}
+
+type queryCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]queryCacheEntry
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+func newQueryCache(ttl time.Duration, maxEntries int) *queryCache {
+	return &queryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]queryCacheEntry),
+	}
+}
+
+func queryCacheKey(query string, args []interface{}) string {
+	return strings.Join(strings.Fields(query), " ") + "|" + fmt.Sprintf("%#v", args)
+}
+
+func copyProducts(products []*ProductWithCategory) []*ProductWithCategory {
+	copied := make([]*ProductWithCategory, len(products))
+	for i, product := range products {
+		clone := *product
+		clone.Metadata = copyMetadata(product.Metadata)
+		copied[i] = &clone
+	}
+	return copied
+}
+
+// copyMetadata deep-copies decoded JSON metadata so cached products never
+// share nested maps or slices with the ones handed to callers.
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = copyJSONValue(value)
+	}
+	return copied
+}
+
+func copyJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyMetadata(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyJSONValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+func (c *queryCache) get(key string) ([]*ProductWithCategory, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	entry, exists := c.entries[key]
+	if exists && time.Now().Before(entry.expires) {
+		c.hits++
+		return copyProducts(entry.products), c.generation, true
+	}
+	if exists {
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+func (c *queryCache) put(key string, generation uint64, products []*ProductWithCategory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if generation != c.generation {
+		return
+	}
+	
+	now := time.Now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		oldestKey := ""
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expires.Before(oldest) {
+				oldestKey, oldest = k, entry.expires
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	
+	c.entries[key] = queryCacheEntry{products: copyProducts(products), expires: now.Add(c.ttl)}
+}
+
+func (c *queryCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.generation++
+	if len(c.entries) > 0 {
+		c.entries = make(map[string]queryCacheEntry)
+	}
+}
+
+func (c *queryCache) stats() QueryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	return QueryCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
 }
 
 type Migration struct {
@@ -51,78 +236,429 @@ type Migration struct {
 }
 
 type QueryBuilder struct {
-	query     strings.Builder
-	args      []interface{}
-	whereUsed bool
+	fields     []string
+	table      string
+	joins      []string
+	conditions []Condition
+	args       []interface{}
+	groupBy    []string
+	having     []string
+	havingArgs []interface{}
+	orderBy    []string
+	limit      *int
+	offset     *int
+	allowed    map[string]bool
+	exprCols   []string
+	dialect    Dialect
+}
+
+type Dialect int
+
+const (
+	DialectSQLite Dialect = iota
+	DialectPostgres
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectSQLite:
+		return "sqlite"
+	case DialectPostgres:
+		return "postgres"
+	default:
+		return fmt.Sprintf("Dialect(%d)", int(d))
+	}
+}
+
+func (d Dialect) Placeholder(n int) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (d Dialect) QuoteIdent(name string) string {
+	if d != DialectPostgres {
+		return name
+	}
+	
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+type Condition struct {
+	render  func(quote func(string) string) string
+	args    []interface{}
+	columns []string
+}
+
+func rawCondition(expr string) Condition {
+	return Condition{render: func(func(string) string) string { return expr }}
+}
+
+func Eq(column string, value interface{}) Condition {
+	return compare(column, "=", value)
+}
+
+func Gt(column string, value interface{}) Condition {
+	return compare(column, ">", value)
+}
+
+func Gte(column string, value interface{}) Condition {
+	return compare(column, ">=", value)
+}
+
+func Lt(column string, value interface{}) Condition {
+	return compare(column, "<", value)
+}
+
+func Lte(column string, value interface{}) Condition {
+	return compare(column, "<=", value)
+}
+
+func compare(column, op string, value interface{}) Condition {
+	return Condition{
+		render: func(quote func(string) string) string {
+			return quote(column) + " " + op + " ?"
+		},
+		args:    []interface{}{value},
+		columns: []string{column},
+	}
+}
+
+func Between(column string, low, high interface{}) Condition {
+	return Condition{
+		render: func(quote func(string) string) string {
+			return quote(column) + " BETWEEN ? AND ?"
+		},
+		args:    []interface{}{low, high},
+		columns: []string{column},
+	}
+}
+
+func In(column string, values ...interface{}) Condition {
+	if len(values) == 0 {
+		cond := rawCondition("1 = 0")
+		cond.columns = []string{column}
+		return cond
+	}
+	
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return Condition{
+		render: func(quote func(string) string) string {
+			return quote(column) + " IN (" + placeholders + ")"
+		},
+		args:    values,
+		columns: []string{column},
+	}
+}
+
+func And(conditions ...Condition) Condition {
+	return combine("AND", "1 = 1", conditions)
+}
+
+func Or(conditions ...Condition) Condition {
+	return combine("OR", "1 = 0", conditions)
+}
+
+func combine(op, empty string, conditions []Condition) Condition {
+	if len(conditions) == 0 {
+		return rawCondition(empty)
+	}
+	
+	var combined Condition
+	for _, cond := range conditions {
+		combined.args = append(combined.args, cond.args...)
+		combined.columns = append(combined.columns, cond.columns...)
+	}
+	combined.render = func(quote func(string) string) string {
+		parts := make([]string, 0, len(conditions))
+		for _, cond := range conditions {
+			parts = append(parts, cond.render(quote))
+		}
+		return "(" + strings.Join(parts, " "+op+" ") + ")"
+	}
+	return combined
 }
 
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
 func NewQueryBuilder() *QueryBuilder {
 	return &QueryBuilder{
 		args: make([]interface{}, 0),
 	}
 }
 
+func (qb *QueryBuilder) WithDialect(dialect Dialect) *QueryBuilder {
+	qb.dialect = dialect
+	return qb
+}
+
 func (qb *QueryBuilder) Select(fields ...string) *QueryBuilder {
-	qb.query.WriteString("SELECT ")
-	qb.query.WriteString(strings.Join(fields, ", "))
+	qb.fields = append(qb.fields, fields...)
 	return qb
 }
 
 func (qb *QueryBuilder) From(table string) *QueryBuilder {
-	qb.query.WriteString(" FROM ")
-	qb.query.WriteString(table)
+	qb.table = table
 	return qb
 }
 
 func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuilder {
-	if qb.whereUsed {
-		qb.query.WriteString(" AND ")
-	} else {
-		qb.query.WriteString(" WHERE ")
-		qb.whereUsed = true
-	}
-	qb.query.WriteString(condition)
+	qb.conditions = append(qb.conditions, rawCondition(condition))
 	qb.args = append(qb.args, args...)
 	return qb
 }
 
+func (qb *QueryBuilder) AllowColumns(columns ...string) *QueryBuilder {
+	if qb.allowed == nil {
+		qb.allowed = make(map[string]bool)
+	}
+	for _, column := range columns {
+		qb.allowed[column] = true
+	}
+	return qb
+}
+
+func (qb *QueryBuilder) WhereExpr(cond Condition) *QueryBuilder {
+	qb.conditions = append(qb.conditions, cond)
+	qb.args = append(qb.args, cond.args...)
+	qb.exprCols = append(qb.exprCols, cond.columns...)
+	return qb
+}
+
+func (qb *QueryBuilder) GroupBy(fields ...string) *QueryBuilder {
+	qb.groupBy = append(qb.groupBy, fields...)
+	return qb
+}
+
+func (qb *QueryBuilder) Having(condition string, args ...interface{}) *QueryBuilder {
+	qb.having = append(qb.having, condition)
+	qb.havingArgs = append(qb.havingArgs, args...)
+	return qb
+}
+
 func (qb *QueryBuilder) OrderBy(field string, desc bool) *QueryBuilder {
-	qb.query.WriteString(" ORDER BY ")
-	qb.query.WriteString(field)
 	if desc {
-		qb.query.WriteString(" DESC")
+		field += " DESC"
 	}
+	qb.orderBy = append(qb.orderBy, field)
 	return qb
 }
 
 func (qb *QueryBuilder) Limit(limit int) *QueryBuilder {
-	qb.query.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+	qb.limit = &limit
 	return qb
 }
 
 func (qb *QueryBuilder) Offset(offset int) *QueryBuilder {
-	qb.query.WriteString(fmt.Sprintf(" OFFSET %d", offset))
+	qb.offset = &offset
 	return qb
 }
 
 func (qb *QueryBuilder) Join(join string) *QueryBuilder {
-	qb.query.WriteString(" ")
-	qb.query.WriteString(join)
+	qb.joins = append(qb.joins, join)
 	return qb
 }
 
-func (qb *QueryBuilder) Build() (string, []interface{}) {
-	return qb.query.String(), qb.args
+func (qb *QueryBuilder) Build() (string, []interface{}, error) {
+	for _, column := range qb.exprCols {
+		if !identifierPattern.MatchString(column) {
+			return "", nil, fmt.Errorf("invalid column name in condition: %q", column)
+		}
+		if !qb.allowed[column] {
+			return "", nil, fmt.Errorf("column %q is not allowed in conditions", column)
+		}
+	}
+	
+	var query strings.Builder
+	
+	query.WriteString("SELECT ")
+	query.WriteString(strings.Join(qb.quoteAll(qb.fields), ", "))
+	query.WriteString(" FROM ")
+	query.WriteString(qb.quoteTable(qb.table))
+	
+	for _, join := range qb.joins {
+		query.WriteString(" ")
+		query.WriteString(join)
+	}
+	
+	if len(qb.conditions) > 0 {
+		conditions := make([]string, len(qb.conditions))
+		for i, cond := range qb.conditions {
+			conditions[i] = cond.render(qb.quoteColumn)
+		}
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conditions, " AND "))
+	}
+	
+	if len(qb.groupBy) > 0 {
+		query.WriteString(" GROUP BY ")
+		query.WriteString(strings.Join(qb.quoteAll(qb.groupBy), ", "))
+	}
+	
+	if len(qb.having) > 0 {
+		query.WriteString(" HAVING ")
+		query.WriteString(strings.Join(qb.having, " AND "))
+	}
+	
+	if len(qb.orderBy) > 0 {
+		orderBy := make([]string, len(qb.orderBy))
+		for i, field := range qb.orderBy {
+			if column, ok := strings.CutSuffix(field, " DESC"); ok {
+				orderBy[i] = qb.quoteColumn(column) + " DESC"
+			} else {
+				orderBy[i] = qb.quoteColumn(field)
+			}
+		}
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(orderBy, ", "))
+	}
+	
+	if qb.limit != nil {
+		query.WriteString(fmt.Sprintf(" LIMIT %d", *qb.limit))
+	}
+	
+	if qb.offset != nil {
+		query.WriteString(fmt.Sprintf(" OFFSET %d", *qb.offset))
+	}
+	
+	args := make([]interface{}, 0, len(qb.args)+len(qb.havingArgs))
+	args = append(args, qb.args...)
+	args = append(args, qb.havingArgs...)
+	
+	if placeholders := countPlaceholders(query.String()); placeholders != len(args) {
+		return "", nil, fmt.Errorf("query has %d placeholders but %d args", placeholders, len(args))
+	}
+	
+	return rewritePlaceholders(query.String(), qb.dialect), args, nil
+}
+
+var aliasPattern = regexp.MustCompile(`^(\S+)\s+(?i:as\s+)?([A-Za-z_][A-Za-z0-9_]*)$`)
+
+func (qb *QueryBuilder) quoteColumn(column string) string {
+	if qb.dialect == DialectSQLite {
+		return column
+	}
+	
+	if identifierPattern.MatchString(column) || strings.HasSuffix(column, ".*") && identifierPattern.MatchString(strings.TrimSuffix(column, ".*")) {
+		return qb.dialect.QuoteIdent(column)
+	}
+	
+	if m := aliasPattern.FindStringSubmatch(column); m != nil && identifierPattern.MatchString(m[1]) {
+		return qb.dialect.QuoteIdent(m[1]) + " AS " + qb.dialect.QuoteIdent(m[2])
+	}
+	
+	return column
+}
+
+func (qb *QueryBuilder) quoteAll(columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = qb.quoteColumn(column)
+	}
+	return quoted
+}
+
+func (qb *QueryBuilder) quoteTable(table string) string {
+	if qb.dialect == DialectSQLite {
+		return table
+	}
+	
+	if m := aliasPattern.FindStringSubmatch(table); m != nil && identifierPattern.MatchString(m[1]) {
+		return qb.dialect.QuoteIdent(m[1]) + " " + qb.dialect.QuoteIdent(m[2])
+	}
+	return qb.quoteColumn(table)
+}
+
+func rewritePlaceholders(query string, dialect Dialect) string {
+	if dialect == DialectSQLite {
+		return query
+	}
+	
+	var rewritten strings.Builder
+	last := 0
+	for n, offset := range placeholderOffsets(query) {
+		rewritten.WriteString(query[last:offset])
+		rewritten.WriteString(dialect.Placeholder(n + 1))
+		last = offset + 1
+	}
+	rewritten.WriteString(query[last:])
+	return rewritten.String()
+}
+
+func countPlaceholders(query string) int {
+	return len(placeholderOffsets(query))
+}
+
+// placeholderOffsets returns the byte offset of every ? bind parameter in
+// query, skipping string literals, quoted identifiers and comments. A doubled
+// quote inside a literal closes and reopens it, which scans the same way.
+func placeholderOffsets(query string) []int {
+	var offsets []int
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return offsets
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return offsets
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return offsets
+			}
+			i += end + 3
+		case c == '?':
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
 }
 
 func NewDatabaseManager(dataSourceName string) (*DatabaseManager, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+	return NewDatabaseManagerWithLogger(dataSourceName, log.Default())
+}
+
+func NewDatabaseManagerWithLogger(dataSourceName string, logger Logger) (*DatabaseManager, error) {
+	return NewDatabaseManagerWithConfig(Config{DataSourceName: dataSourceName, Logger: logger})
+}
+
+func NewDatabaseManagerWithConfig(cfg Config) (*DatabaseManager, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	
+	pragmas := cfg.Pragmas
+	if pragmas == nil {
+		pragmas = defaultPragmas()
+	}
+	
+	hook, err := pragmaHook(pragmas)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 	
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	db := sql.OpenDB(&sqliteConnector{
+		driver: &sqlite3.SQLiteDriver{ConnectHook: hook},
+		dsn:    cfg.DataSourceName,
+	})
+	
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
 	db.SetConnMaxLifetime(5 * time.Minute)
 	
 	if err := db.Ping(); err != nil {
@@ -133,6 +669,23 @@ func NewDatabaseManager(dataSourceName string) (*DatabaseManager, error) {
 		db:           db,
 		transactions: make(map[string]*sql.Tx),
 		migrations:   getMigrations(),
+		Logger:       logger,
+		slowQuery:    cfg.SlowQueryThreshold,
+	}
+	
+	if cfg.QueryCacheTTL > 0 {
+		maxEntries := cfg.QueryCacheSize
+		if maxEntries <= 0 {
+			maxEntries = defaultQueryCacheSize
+		}
+		manager.queryCache = newQueryCache(cfg.QueryCacheTTL, maxEntries)
+	}
+	
+	if manager.categories, err = NewRepository[Category](manager, "categories"); err != nil {
+		return nil, err
+	}
+	if manager.products, err = NewRepository[Product](manager, "products"); err != nil {
+		return nil, err
 	}
 	
 	if err := manager.RunMigrations(); err != nil {
@@ -142,6 +695,119 @@ func NewDatabaseManager(dataSourceName string) (*DatabaseManager, error) {
 	return manager, nil
 }
 
+type Config struct {
+	DataSourceName     string
+	Logger             Logger
+	Pragmas            map[string]string
+	SlowQueryThreshold time.Duration
+	QueryCacheTTL      time.Duration
+	QueryCacheSize     int
+}
+
+const defaultQueryCacheSize = 256
+
+func defaultPragmas() map[string]string {
+	return map[string]string{
+		"foreign_keys": "ON",
+		"journal_mode": "WAL",
+	}
+}
+
+var pragmaValuePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func pragmaHook(pragmas map[string]string) (func(*sqlite3.SQLiteConn) error, error) {
+	names := make([]string, 0, len(pragmas))
+	for name, value := range pragmas {
+		if !identifierPattern.MatchString(name) || strings.Contains(name, ".") {
+			return nil, fmt.Errorf("invalid pragma name: %q", name)
+		}
+		if !pragmaValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid value for pragma %s: %q", name, value)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	statements := make([]string, 0, len(names))
+	for _, name := range names {
+		statements = append(statements, fmt.Sprintf("PRAGMA %s = %s", name, pragmas[name]))
+	}
+	
+	return func(conn *sqlite3.SQLiteConn) error {
+		for _, statement := range statements {
+			if _, err := conn.Exec(statement, nil); err != nil {
+				return fmt.Errorf("failed to apply %q: %w", statement, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+type sqliteConnector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (dm *DatabaseManager) exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := dm.db.Exec(query, args...)
+	dm.observeQuery(query, len(args), start)
+	dm.invalidateQueryCache()
+	return result, err
+}
+
+func (dm *DatabaseManager) invalidateQueryCache() {
+	if dm.queryCache != nil {
+		dm.queryCache.invalidate()
+	}
+}
+
+func (dm *DatabaseManager) QueryCacheStats() QueryCacheStats {
+	if dm.queryCache == nil {
+		return QueryCacheStats{}
+	}
+	return dm.queryCache.stats()
+}
+
+func (dm *DatabaseManager) query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := dm.db.Query(query, args...)
+	dm.observeQuery(query, len(args), start)
+	return rows, err
+}
+
+func (dm *DatabaseManager) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := dm.db.QueryContext(ctx, query, args...)
+	dm.observeQuery(query, len(args), start)
+	return rows, err
+}
+
+func (dm *DatabaseManager) queryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := dm.db.QueryRow(query, args...)
+	dm.observeQuery(query, len(args), start)
+	return row
+}
+
+func (dm *DatabaseManager) observeQuery(query string, argCount int, start time.Time) {
+	if dm.slowQuery <= 0 {
+		return
+	}
+	
+	if elapsed := time.Since(start); elapsed > dm.slowQuery {
+		dm.Logger.Printf("Slow query (%v, %d args): %s", elapsed, argCount, strings.Join(strings.Fields(query), " "))
+	}
+}
+
 func getMigrations() []Migration {
 	return []Migration{
 		{
@@ -195,181 +861,706 @@ func getMigrations() []Migration {
 				CREATE INDEX IF NOT EXISTS idx_products_price ON products(price);
 			`,
 		},
+		{
+			Version: 5,
+			Name:    "add_product_metadata",
+			SQL: `
+				ALTER TABLE products ADD COLUMN metadata TEXT;
+			`,
+		},
+		{
+			Version: 6,
+			Name:    "create_stock_adjustments_table",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS stock_adjustments (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					product_id INTEGER NOT NULL,
+					delta INTEGER NOT NULL,
+					reason TEXT NOT NULL,
+					at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (product_id) REFERENCES products (id)
+				);
+				CREATE INDEX IF NOT EXISTS idx_stock_adjustments_product_id ON stock_adjustments(product_id);
+			`,
+		},
+	}
+}
+
+func (dm *DatabaseManager) RunMigrations() error {
+	dm.schemaMu.Lock()
+	defer dm.schemaMu.Unlock()
+	
+	dm.Logger.Printf("Running database migrations...")
+	
+	_, err := dm.exec(`
+		CREATE TABLE IF NOT EXISTS migration_history (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migration history table: %w", err)
+	}
+	
+	appliedMigrations, err := dm.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	
+	for _, migration := range dm.migrations {
+		if appliedMigrations[migration.Version] {
+			continue
+		}
+		
+		dm.Logger.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+		
+		tx, err := dm.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration %d: %w", migration.Version, err)
+		}
+		
+		if _, err := tx.Exec(migration.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to execute migration %d: %w", migration.Version, err)
+		}
+		
+		if _, err := tx.Exec("INSERT INTO migration_history (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+		}
+	}
+	
+	dm.mu.Lock()
+	dm.migrated = true
+	dm.mu.Unlock()
+	
+	dm.Logger.Printf("Migrations completed successfully")
+	return nil
+}
+
+func (dm *DatabaseManager) WarmUp(n int) error {
+	if n > maxOpenConns {
+		n = maxOpenConns
+	}
+	if n > maxIdleConns {
+		dm.db.SetMaxIdleConns(n)
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	
+	for i := 0; i < n; i++ {
+		conn, err := dm.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open connection %d: %w", i+1, err)
+		}
+		conns = append(conns, conn)
+		
+		if err := conn.PingContext(ctx); err != nil {
+			return fmt.Errorf("failed to ping connection %d: %w", i+1, err)
+		}
+	}
+	
+	dm.mu.Lock()
+	dm.warmedUp = true
+	dm.mu.Unlock()
+	
+	dm.Logger.Printf("Warmed up %d database connections", n)
+	return nil
+}
+
+func (dm *DatabaseManager) Ready() bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	
+	return dm.migrated && dm.warmedUp
+}
+
+func (dm *DatabaseManager) RunMigrationsDryRun() ([]Migration, error) {
+	var count int
+	err := dm.queryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migration_history'").Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check migration history table: %w", err)
+	}
+	
+	appliedMigrations := make(map[int]bool)
+	if count > 0 {
+		appliedMigrations, err = dm.appliedMigrations()
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	pending := make([]Migration, 0)
+	for _, migration := range dm.migrations {
+		if appliedMigrations[migration.Version] {
+			continue
+		}
+		pending = append(pending, migration)
+	}
+	
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Version < pending[j].Version
+	})
+	
+	for _, migration := range pending {
+		dm.Logger.Printf("Pending migration %d: %s", migration.Version, migration.Name)
+	}
+	
+	return pending, nil
+}
+
+func (dm *DatabaseManager) appliedMigrations() (map[int]bool, error) {
+	appliedMigrations := make(map[int]bool)
+	rows, err := dm.query("SELECT version FROM migration_history")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migration history: %w", err)
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		appliedMigrations[version] = true
+	}
+	
+	return appliedMigrations, rows.Err()
+}
+
+type repoColumn struct {
+	name     string
+	index    []int
+	key      bool
+	readOnly bool
+	updated  bool
+	json     bool
+}
+
+type Repository[T any] struct {
+	dm      *DatabaseManager
+	table   string
+	entity  string
+	columns []repoColumn
+	key     repoColumn
+}
+
+func NewRepository[T any](dm *DatabaseManager, table string) (*Repository[T], error) {
+	if !identifierPattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name: %q", table)
+	}
+	
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("repository type %s is not a struct", t)
+	}
+	
+	columns, err := structColumns(t)
+	if err != nil {
+		return nil, err
+	}
+	
+	repo := &Repository[T]{
+		dm:      dm,
+		table:   table,
+		entity:  strings.ToLower(t.Name()),
+		columns: columns,
+	}
+	
+	hasKey := false
+	for _, column := range columns {
+		if !column.key {
+			continue
+		}
+		if hasKey {
+			return nil, fmt.Errorf("%s has more than one key column", t.Name())
+		}
+		repo.key = column
+		hasKey = true
+	}
+	
+	if !hasKey {
+		return nil, fmt.Errorf("%s has no db key column", t.Name())
 	}
+	
+	return repo, nil
+}
+
+var structColumnsCache sync.Map
+
+func structColumns(t reflect.Type) ([]repoColumn, error) {
+	if cached, ok := structColumnsCache.Load(t); ok {
+		return cached.([]repoColumn), nil
+	}
+	
+	var columns []repoColumn
+	for _, field := range reflect.VisibleFields(t) {
+		tag, ok := field.Tag.Lookup("db")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		
+		parts := strings.Split(tag, ",")
+		column := repoColumn{name: parts[0], index: field.Index}
+		if !identifierPattern.MatchString(column.name) || strings.Contains(column.name, ".") {
+			return nil, fmt.Errorf("invalid column name %q on %s.%s", column.name, t.Name(), field.Name)
+		}
+		for _, option := range parts[1:] {
+			switch option {
+			case "key":
+				column.key = true
+			case "readonly":
+				column.readOnly = true
+			case "updated":
+				column.updated = true
+			case "json":
+				column.json = true
+			default:
+				return nil, fmt.Errorf("unknown db tag option %q on %s.%s", option, t.Name(), field.Name)
+			}
+		}
+		columns = append(columns, column)
+	}
+	
+	structColumnsCache.Store(t, columns)
+	return columns, nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func ScanStruct(rows *sql.Rows, dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	value = value.Elem()
+	
+	columns, err := structColumns(value.Type())
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]repoColumn, len(columns))
+	for _, column := range columns {
+		byName[column.name] = column
+	}
+	
+	names, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read columns: %w", err)
+	}
+	
+	targets := make([]interface{}, len(names))
+	var assign []func() error
+	for i, name := range names {
+		column, ok := byName[name]
+		if !ok {
+			targets[i] = new(interface{})
+			continue
+		}
+		
+		field := value.FieldByIndex(column.index)
+		switch {
+		case column.json:
+			raw := new(sql.NullString)
+			targets[i] = raw
+			assign = append(assign, func() error {
+				if !raw.Valid || strings.TrimSpace(raw.String) == "" {
+					field.Set(reflect.Zero(field.Type()))
+					return nil
+				}
+				if err := json.Unmarshal([]byte(raw.String), field.Addr().Interface()); err != nil {
+					return fmt.Errorf("failed to decode %s: %w", column.name, err)
+				}
+				return nil
+			})
+		case field.Kind() == reflect.Pointer || reflect.PointerTo(field.Type()).Implements(scannerType):
+			targets[i] = field.Addr().Interface()
+		default:
+			nullable := reflect.New(reflect.PointerTo(field.Type()))
+			targets[i] = nullable.Interface()
+			assign = append(assign, func() error {
+				if ptr := nullable.Elem(); !ptr.IsNil() {
+					field.Set(ptr.Elem())
+				} else {
+					field.Set(reflect.Zero(field.Type()))
+				}
+				return nil
+			})
+		}
+	}
+	
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	
+	for _, fn := range assign {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository[T]) columnList() string {
+	names := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		names[i] = column.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (r *Repository[T]) column(name string) (repoColumn, bool) {
+	for _, column := range r.columns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return repoColumn{}, false
+}
+
+func (r *Repository[T]) writable(column repoColumn) bool {
+	return !column.key && !column.readOnly && !column.updated
+}
+
+func (r *Repository[T]) encode(column repoColumn, value interface{}) (interface{}, error) {
+	if !column.json {
+		return value, nil
+	}
+	
+	switch value.(type) {
+	case nil, string, []byte:
+		return value, nil
+	}
+	
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface:
+		if v.IsNil() || (v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface && v.Len() == 0) {
+			return nil, nil
+		}
+	}
+	
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", column.name, err)
+	}
+	return string(data), nil
+}
+
+func (r *Repository[T]) Create(entity *T) (*T, error) {
+	value := reflect.ValueOf(entity).Elem()
+	
+	names := make([]string, 0, len(r.columns))
+	args := make([]interface{}, 0, len(r.columns))
+	for _, column := range r.columns {
+		if !r.writable(column) {
+			continue
+		}
+		arg, err := r.encode(column, value.FieldByIndex(column.index).Interface())
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, column.name)
+		args = append(args, arg)
+	}
+	
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		r.table, strings.Join(names, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+	
+	result, err := r.dm.exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", r.entity, err)
+	}
+	
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	
+	return r.Get(int(id))
+}
+
+func (r *Repository[T]) Get(id interface{}) (*T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", r.columnList(), r.table, r.key.name)
+	
+	rows, err := r.dm.query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", r.entity, err)
+	}
+	defer rows.Close()
+	
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", r.entity, err)
+		}
+		return nil, fmt.Errorf("%s with ID %v not found", r.entity, id)
+	}
+	
+	entity := new(T)
+	if err := ScanStruct(rows, entity); err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", r.entity, err)
+	}
+	
+	return entity, nil
+}
+
+func (r *Repository[T]) Update(id interface{}, updates map[string]interface{}) (*T, error) {
+	if len(updates) == 0 {
+		return r.Get(id)
+	}
+	
+	names := make([]string, 0, len(updates))
+	for name := range updates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	setParts := make([]string, 0, len(names)+1)
+	args := make([]interface{}, 0, len(names)+1)
+	for _, name := range names {
+		column, ok := r.column(name)
+		if !ok || !r.writable(column) {
+			return nil, fmt.Errorf("column %q cannot be updated on %s", name, r.table)
+		}
+		arg, err := r.encode(column, updates[name])
+		if err != nil {
+			return nil, err
+		}
+		setParts = append(setParts, column.name+" = ?")
+		args = append(args, arg)
+	}
+	
+	for _, column := range r.columns {
+		if column.updated {
+			setParts = append(setParts, column.name+" = CURRENT_TIMESTAMP")
+		}
+	}
+	args = append(args, id)
+	
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", r.table, strings.Join(setParts, ", "), r.key.name)
+	
+	if _, err := r.dm.exec(query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", r.entity, err)
+	}
+	
+	return r.Get(id)
+}
+
+func (r *Repository[T]) Delete(id interface{}) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", r.table, r.key.name)
+	
+	result, err := r.dm.exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", r.entity, err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s with ID %v not found", r.entity, id)
+	}
+	
+	return nil
+}
+
+func (r *Repository[T]) selectOrdered(orderBy string) (string, error) {
+	if orderBy == "" {
+		orderBy = r.key.name
+	}
+	if _, ok := r.column(orderBy); !ok {
+		return "", fmt.Errorf("cannot order %s by unknown column %q", r.table, orderBy)
+	}
+	
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", r.columnList(), r.table, orderBy), nil
 }
 
-func (dm *DatabaseManager) RunMigrations() error {
-	log.Println("Running database migrations...")
-	
-	_, err := dm.db.Exec(`
-		CREATE TABLE IF NOT EXISTS migration_history (
-			version INTEGER PRIMARY KEY,
-			name TEXT NOT NULL,
-			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
+func (r *Repository[T]) List(orderBy string) ([]*T, error) {
+	query, err := r.selectOrdered(orderBy)
 	if err != nil {
-		return fmt.Errorf("failed to create migration history table: %w", err)
+		return nil, err
 	}
-	
-	appliedMigrations := make(map[int]bool)
-	rows, err := dm.db.Query("SELECT version FROM migration_history")
-	if err != nil {
-		return fmt.Errorf("failed to query migration history: %w", err)
+	return r.collect(query)
+}
+
+func (r *Repository[T]) Page(orderBy string, limit, offset int) ([]*T, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative, got %d", offset)
 	}
-	defer rows.Close()
 	
-	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
-			return fmt.Errorf("failed to scan migration version: %w", err)
-		}
-		appliedMigrations[version] = true
+	query, err := r.selectOrdered(orderBy)
+	if err != nil {
+		return nil, err
 	}
+	return r.collect(query+" LIMIT ? OFFSET ?", limit, offset)
+}
+
+func (r *Repository[T]) Stream(ctx context.Context, orderBy string) (<-chan *T, <-chan error) {
+	out := make(chan *T)
+	errs := make(chan error, 1)
 	
-	for _, migration := range dm.migrations {
-		if appliedMigrations[migration.Version] {
-			continue
-		}
+	go func() {
+		defer close(errs)
+		defer close(out)
 		
-		log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
-		
-		tx, err := dm.db.Begin()
+		query, err := r.selectOrdered(orderBy)
 		if err != nil {
-			return fmt.Errorf("failed to begin transaction for migration %d: %w", migration.Version, err)
+			errs <- err
+			return
 		}
 		
-		if _, err := tx.Exec(migration.SQL); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to execute migration %d: %w", migration.Version, err)
+		rows, err := r.dm.queryContext(ctx, query)
+		if err != nil {
+			errs <- fmt.Errorf("failed to query %s: %w", r.table, err)
+			return
 		}
+		defer rows.Close()
 		
-		if _, err := tx.Exec("INSERT INTO migration_history (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		for rows.Next() {
+			entity := new(T)
+			if err := ScanStruct(rows, entity); err != nil {
+				errs <- fmt.Errorf("failed to scan %s: %w", r.entity, err)
+				return
+			}
+			
+			select {
+			case out <- entity:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
 		}
 		
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+		if err := rows.Err(); err != nil {
+			errs <- fmt.Errorf("failed to stream %s: %w", r.table, err)
 		}
-	}
+	}()
 	
-	log.Println("Migrations completed successfully")
-	return nil
+	return out, errs
 }
 
-func (dm *DatabaseManager) CreateCategory(name, description string) (*Category, error) {
-	query := `
-		INSERT INTO categories (name, description)
-		VALUES (?, ?)
-	`
-	
-	result, err := dm.db.Exec(query, name, description)
+func (r *Repository[T]) collect(query string, args ...interface{}) ([]*T, error) {
+	rows, err := r.dm.query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create category: %w", err)
+		return nil, fmt.Errorf("failed to query %s: %w", r.table, err)
 	}
+	defer rows.Close()
 	
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	var entities []*T
+	for rows.Next() {
+		entity := new(T)
+		if err := ScanStruct(rows, entity); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", r.entity, err)
+		}
+		entities = append(entities, entity)
 	}
 	
-	return dm.GetCategoryByID(int(id))
+	return entities, rows.Err()
+}
+
+func (dm *DatabaseManager) CreateCategory(name, description string) (*Category, error) {
+	return dm.categories.Create(&Category{Name: name, Description: description})
 }
 
 func (dm *DatabaseManager) GetCategoryByID(id int) (*Category, error) {
-	query := `
-		SELECT id, name, description, created_at, updated_at
-		FROM categories
-		WHERE id = ?
-	`
-	
-	var category Category
-	err := dm.db.QueryRow(query, id).Scan(
-		&category.ID,
-		&category.Name,
-		&category.Description,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
-	
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("category with ID %d not found", id)
-		}
-		return nil, fmt.Errorf("failed to get category: %w", err)
-	}
-	
-	return &category, nil
+	return dm.categories.Get(id)
 }
 
 func (dm *DatabaseManager) GetAllCategories() ([]*Category, error) {
-	query := `
-		SELECT id, name, description, created_at, updated_at
-		FROM categories
-		ORDER BY name
-	`
-	
-	rows, err := dm.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query categories: %w", err)
+	return dm.categories.List("name")
+}
+
+func (dm *DatabaseManager) GetCategories(limit, offset int) ([]*Category, error) {
+	return dm.categories.Page("name", limit, offset)
+}
+
+func (dm *DatabaseManager) StreamCategories(ctx context.Context) (<-chan *Category, <-chan error) {
+	return dm.categories.Stream(ctx, "name")
+}
+
+func (dm *DatabaseManager) CreateProduct(product *Product) (*Product, error) {
+	return dm.products.Create(product)
+}
+
+func (dm *DatabaseManager) CreateProductReturning(product *Product) (*Product, error) {
+	if !dm.SupportsReturning() {
+		return dm.CreateProduct(product)
 	}
-	defer rows.Close()
 	
-	var categories []*Category
-	for rows.Next() {
-		var category Category
-		err := rows.Scan(
-			&category.ID,
-			&category.Name,
-			&category.Description,
-			&category.CreatedAt,
-			&category.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan category: %w", err)
-		}
-		categories = append(categories, &category)
+	metadata, err := encodeMetadata(product.Metadata)
+	if err != nil {
+		return nil, err
 	}
 	
-	return categories, nil
-}
-
-func (dm *DatabaseManager) CreateProduct(product *Product) (*Product, error) {
 	query := `
-		INSERT INTO products (name, description, price, stock, category_id, is_active)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
+		INSERT INTO products (name, description, price, stock, category_id, is_active, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + productColumns
 	
-	result, err := dm.db.Exec(query,
+	var created Product
+	row := dm.queryRow(query,
 		product.Name,
 		product.Description,
 		product.Price,
 		product.Stock,
 		product.CategoryID,
 		product.IsActive,
+		metadata,
 	)
-	if err != nil {
+	if err := scanProduct(row, &created); err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
+	dm.invalidateQueryCache()
 	
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
-	}
-	
-	return dm.GetProductByID(int(id))
+	return &created, nil
 }
 
-func (dm *DatabaseManager) GetProductByID(id int) (*Product, error) {
-	query := `
-		SELECT id, name, description, price, stock, category_id, created_at, updated_at, is_active
-		FROM products
-		WHERE id = ?
-	`
-	
-	var product Product
-	err := dm.db.QueryRow(query, id).Scan(
+func (dm *DatabaseManager) SupportsReturning() bool {
+	dm.returningOnce.Do(func() {
+		var version string
+		if err := dm.queryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+			dm.Logger.Printf("Failed to detect SQLite version: %v", err)
+			return
+		}
+		
+		var major, minor int
+		if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+			dm.Logger.Printf("Failed to parse SQLite version %q: %v", version, err)
+			return
+		}
+		
+		dm.supportsReturning = major > 3 || (major == 3 && minor >= 35)
+	})
+	return dm.supportsReturning
+}
+
+const productColumns = "id, name, description, price, stock, category_id, created_at, updated_at, is_active, metadata"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner, product *Product, extra ...interface{}) error {
+	var metadata sql.NullString
+	dest := []interface{}{
 		&product.ID,
 		&product.Name,
 		&product.Description,
@@ -379,41 +1570,87 @@ func (dm *DatabaseManager) GetProductByID(id int) (*Product, error) {
 		&product.CreatedAt,
 		&product.UpdatedAt,
 		&product.IsActive,
-	)
+		&metadata,
+	}
+	
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
 	
+	decoded, err := decodeMetadata(metadata)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product with ID %d not found", id)
-		}
-		return nil, fmt.Errorf("failed to get product: %w", err)
+		return err
+	}
+	product.Metadata = decoded
+	return nil
+}
+
+func encodeMetadata(metadata map[string]interface{}) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeMetadata(metadata sql.NullString) (map[string]interface{}, error) {
+	if !metadata.Valid || strings.TrimSpace(metadata.String) == "" {
+		return nil, nil
 	}
 	
-	return &product, nil
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata.String), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	return decoded, nil
+}
+
+func (dm *DatabaseManager) GetProductByID(id int) (*Product, error) {
+	return dm.products.Get(id)
 }
 
 func (dm *DatabaseManager) GetProductsWithCategory(limit, offset int, categoryID *int, minPrice, maxPrice *float64) ([]*ProductWithCategory, error) {
 	qb := NewQueryBuilder()
-	qb.Select("p.id", "p.name", "p.description", "p.price", "p.stock", "p.category_id", "p.created_at", "p.updated_at", "p.is_active", "c.name as category_name")
+	qb.Select("p.id", "p.name", "p.description", "p.price", "p.stock", "p.category_id", "p.created_at", "p.updated_at", "p.is_active", "p.metadata", "c.name as category_name")
 	qb.From("products p")
 	qb.Join("JOIN categories c ON p.category_id = c.id")
+	qb.AllowColumns("p.category_id", "p.price")
 	
 	if categoryID != nil {
-		qb.Where("p.category_id = ?", *categoryID)
+		qb.WhereExpr(Eq("p.category_id", *categoryID))
 	}
 	
 	if minPrice != nil {
-		qb.Where("p.price >= ?", *minPrice)
+		qb.WhereExpr(Gte("p.price", *minPrice))
 	}
 	
 	if maxPrice != nil {
-		qb.Where("p.price <= ?", *maxPrice)
+		qb.WhereExpr(Lte("p.price", *maxPrice))
 	}
 	
 	qb.OrderBy("p.name", false).Limit(limit).Offset(offset)
 	
-	query, args := qb.Build()
+	query, args, err := qb.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build products query: %w", err)
+	}
 	
-	rows, err := dm.db.Query(query, args...)
+	var cacheKey string
+	var generation uint64
+	if dm.queryCache != nil {
+		cacheKey = queryCacheKey(query, args)
+		var cached []*ProductWithCategory
+		var ok bool
+		if cached, generation, ok = dm.queryCache.get(cacheKey); ok {
+			return cached, nil
+		}
+	}
+	
+	rows, err := dm.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query products: %w", err)
 	}
@@ -422,71 +1659,150 @@ func (dm *DatabaseManager) GetProductsWithCategory(limit, offset int, categoryID
 	var products []*ProductWithCategory
 	for rows.Next() {
 		var product ProductWithCategory
-		err := rows.Scan(
-			&product.ID,
-			&product.Name,
-			&product.Description,
-			&product.Price,
-			&product.Stock,
-			&product.CategoryID,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-			&product.IsActive,
-			&product.CategoryName,
-		)
-		if err != nil {
+		if err := ScanStruct(rows, &product); err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, &product)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	
+	if dm.queryCache != nil {
+		dm.queryCache.put(cacheKey, generation, products)
+	}
 	
 	return products, nil
 }
 
-func (dm *DatabaseManager) UpdateProduct(id int, updates map[string]interface{}) (*Product, error) {
-	if len(updates) == 0 {
-		return dm.GetProductByID(id)
+func (dm *DatabaseManager) GetProductsByMetadata(key string, value interface{}) ([]*Product, error) {
+	path := `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+	query := `SELECT ` + productColumns + ` FROM products WHERE metadata IS NOT NULL AND json_extract(metadata, ?) = ? ORDER BY name`
+	
+	rows, err := dm.query(query, path, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products by metadata: %w", err)
 	}
+	defer rows.Close()
 	
-	setParts := make([]string, 0, len(updates))
-	args := make([]interface{}, 0, len(updates)+1)
+	var products []*Product
+	for rows.Next() {
+		var product Product
+		if err := ScanStruct(rows, &product); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, &product)
+	}
 	
-	for field, value := range updates {
-		setParts = append(setParts, field+" = ?")
-		args = append(args, value)
+	return products, rows.Err()
+}
+
+func (dm *DatabaseManager) UpdateProduct(id int, updates map[string]interface{}) (*Product, error) {
+	return dm.products.Update(id, updates)
+}
+
+func (dm *DatabaseManager) DeleteProduct(id int) error {
+	return dm.products.Delete(id)
+}
+
+func (dm *DatabaseManager) SetProductsActiveByCategory(categoryID int, active bool) (int64, error) {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 	
-	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
-	args = append(args, id)
+	var exists int
+	err = tx.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ?", categoryID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check category: %w", err)
+	}
+	if exists == 0 {
+		return 0, fmt.Errorf("category with ID %d not found", categoryID)
+	}
 	
-	query := fmt.Sprintf("UPDATE products SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	result, err := tx.Exec(`
+		UPDATE products
+		SET is_active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE category_id = ? AND is_active != ?
+	`, active, categoryID, active)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update products: %w", err)
+	}
 	
-	_, err := dm.db.Exec(query, args...)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product: %w", err)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	dm.invalidateQueryCache()
 	
-	return dm.GetProductByID(id)
+	return rowsAffected, nil
 }
 
-func (dm *DatabaseManager) DeleteProduct(id int) error {
-	query := "DELETE FROM products WHERE id = ?"
+func (dm *DatabaseManager) AdjustStock(adjustments map[int]int, reason string) (map[int]int, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("stock adjustment reason is required")
+	}
 	
-	result, err := dm.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete product: %w", err)
+	productIDs := make([]int, 0, len(adjustments))
+	for productID := range adjustments {
+		productIDs = append(productIDs, productID)
 	}
+	sort.Ints(productIDs)
 	
-	rowsAffected, err := result.RowsAffected()
+	tx, err := dm.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 	
-	if rowsAffected == 0 {
-		return fmt.Errorf("product with ID %d not found", id)
+	levels := make(map[int]int, len(productIDs))
+	for _, productID := range productIDs {
+		delta := adjustments[productID]
+		
+		var stock int
+		err := tx.QueryRow("SELECT stock FROM products WHERE id = ?", productID).Scan(&stock)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("product with ID %d not found", productID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stock for product %d: %w", productID, err)
+		}
+		
+		if stock+delta < 0 {
+			return nil, fmt.Errorf("adjusting product %d by %d would leave negative stock (current %d)", productID, delta, stock)
+		}
+		
+		if delta != 0 {
+			_, err = tx.Exec(`
+				UPDATE products
+				SET stock = stock + ?, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, delta, productID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to adjust stock for product %d: %w", productID, err)
+			}
+			
+			_, err = tx.Exec("INSERT INTO stock_adjustments (product_id, delta, reason) VALUES (?, ?, ?)", productID, delta, reason)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record stock adjustment for product %d: %w", productID, err)
+			}
+		}
+		
+		levels[productID] = stock + delta
 	}
 	
-	return nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	dm.invalidateQueryCache()
+	
+	return levels, nil
 }
 
 func (dm *DatabaseManager) BeginTransaction(txID string) error {
@@ -518,6 +1834,7 @@ func (dm *DatabaseManager) CommitTransaction(txID string) error {
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	dm.invalidateQueryCache()
 	
 	delete(dm.transactions, txID)
 	return nil
@@ -545,12 +1862,12 @@ func (dm *DatabaseManager) GetDatabaseStats() (map[string]interface{}, error) {
 	
 	var categoryCount, productCount int
 	
-	err := dm.db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&categoryCount)
+	err := dm.queryRow("SELECT COUNT(*) FROM categories").Scan(&categoryCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category count: %w", err)
 	}
 	
-	err = dm.db.QueryRow("SELECT COUNT(*) FROM products").Scan(&productCount)
+	err = dm.queryRow("SELECT COUNT(*) FROM products").Scan(&productCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product count: %w", err)
 	}
@@ -558,7 +1875,7 @@ func (dm *DatabaseManager) GetDatabaseStats() (map[string]interface{}, error) {
 	var avgPrice, totalValue sql.NullFloat64
 	var minPrice, maxPrice sql.NullFloat64
 	
-	err = dm.db.QueryRow("SELECT AVG(price), SUM(price * stock), MIN(price), MAX(price) FROM products WHERE is_active = 1").Scan(&avgPrice, &totalValue, &minPrice, &maxPrice)
+	err = dm.queryRow("SELECT AVG(price), SUM(price * stock), MIN(price), MAX(price) FROM products WHERE is_active = 1").Scan(&avgPrice, &totalValue, &minPrice, &maxPrice)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product statistics: %w", err)
 	}
@@ -570,11 +1887,98 @@ func (dm *DatabaseManager) GetDatabaseStats() (map[string]interface{}, error) {
 	stats["min_price"] = minPrice.Float64
 	stats["max_price"] = maxPrice.Float64
 	
+	if dm.queryCache != nil {
+		cache := dm.queryCache.stats()
+		stats["query_cache_hits"] = cache.Hits
+		stats["query_cache_misses"] = cache.Misses
+		stats["query_cache_entries"] = cache.Entries
+	}
+	
 	return stats, nil
 }
 
-func (dm *DatabaseManager) SeedTestData() error {
-	log.Println("Seeding test data...")
+func (dm *DatabaseManager) GetFacets() (*Facets, error) {
+	facets := &Facets{
+		Categories:   make([]CategoryFacet, 0),
+		PriceBuckets: make([]PriceBucket, len(priceBucketBounds)),
+	}
+	
+	rows, err := dm.query(`
+		SELECT c.id, c.name, COUNT(p.id)
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.is_active = 1
+		GROUP BY c.id, c.name
+		ORDER BY c.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category facets: %w", err)
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		var facet CategoryFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.ProductCount); err != nil {
+			return nil, fmt.Errorf("failed to scan category facet: %w", err)
+		}
+		facets.Categories = append(facets.Categories, facet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read category facets: %w", err)
+	}
+	
+	cases := make([]string, 0, len(priceBucketBounds))
+	args := make([]interface{}, 0, len(priceBucketBounds))
+	for i, bound := range priceBucketBounds {
+		facets.PriceBuckets[i].Min = bound
+		if i+1 < len(priceBucketBounds) {
+			facets.PriceBuckets[i].Max = priceBucketBounds[i+1]
+			cases = append(cases, fmt.Sprintf("WHEN p.price < ? THEN %d", i))
+			args = append(args, priceBucketBounds[i+1])
+		} else {
+			facets.PriceBuckets[i].Unbounded = true
+		}
+	}
+	
+	query := fmt.Sprintf(`
+		SELECT CASE %s ELSE %d END AS bucket, COUNT(*)
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.is_active = 1
+		GROUP BY bucket
+	`, strings.Join(cases, " "), len(priceBucketBounds)-1)
+	
+	bucketRows, err := dm.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer bucketRows.Close()
+	
+	for bucketRows.Next() {
+		var bucket, count int
+		if err := bucketRows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price bucket: %w", err)
+		}
+		facets.PriceBuckets[bucket].ProductCount = count
+	}
+	if err := bucketRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read price buckets: %w", err)
+	}
+	
+	return facets, nil
+}
+
+type SeedSummary struct {
+	CategoriesCreated int
+	CategoriesSkipped int
+	ProductsCreated   int
+	ProductsSkipped   int
+}
+
+func (dm *DatabaseManager) SeedTestData() (*SeedSummary, error) {
+	dm.Logger.Printf("Seeding test data...")
+	
+	summary := &SeedSummary{}
 	
 	categories := []struct {
 		name, description string
@@ -587,11 +1991,23 @@ func (dm *DatabaseManager) SeedTestData() error {
 	
 	categoryMap := make(map[string]int)
 	for _, cat := range categories {
+		var existingID int
+		err := dm.queryRow("SELECT id FROM categories WHERE name = ?", cat.name).Scan(&existingID)
+		if err == nil {
+			categoryMap[cat.name] = existingID
+			summary.CategoriesSkipped++
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to look up category %s: %w", cat.name, err)
+		}
+		
 		category, err := dm.CreateCategory(cat.name, cat.description)
 		if err != nil {
-			return fmt.Errorf("failed to create category %s: %w", cat.name, err)
+			return nil, fmt.Errorf("failed to create category %s: %w", cat.name, err)
 		}
 		categoryMap[cat.name] = category.ID
+		summary.CategoriesCreated++
 	}
 	
 	products := []*Product{
@@ -603,20 +2019,120 @@ func (dm *DatabaseManager) SeedTestData() error {
 	}
 	
 	for _, product := range products {
-		_, err := dm.CreateProduct(product)
+		var exists int
+		err := dm.queryRow("SELECT COUNT(*) FROM products WHERE name = ? AND category_id = ?", product.Name, product.CategoryID).Scan(&exists)
 		if err != nil {
-			return fmt.Errorf("failed to create product %s: %w", product.Name, err)
+			return nil, fmt.Errorf("failed to look up product %s: %w", product.Name, err)
 		}
+		if exists > 0 {
+			summary.ProductsSkipped++
+			continue
+		}
+		
+		_, err = dm.CreateProduct(product)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create product %s: %w", product.Name, err)
+		}
+		summary.ProductsCreated++
+	}
+	
+	dm.Logger.Printf("Seeded %d categories (%d skipped) and %d products (%d skipped)",
+		summary.CategoriesCreated, summary.CategoriesSkipped, summary.ProductsCreated, summary.ProductsSkipped)
+	return summary, nil
+}
+
+func (dm *DatabaseManager) StartMaintenance(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("maintenance interval must be positive, got %v", interval)
+	}
+	
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	
+	if dm.maintenanceStop != nil {
+		return fmt.Errorf("maintenance is already running")
 	}
 	
-	log.Printf("Successfully seeded %d categories and %d products", len(categories), len(products))
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	dm.maintenanceStop = stop
+	dm.maintenanceDone = done
+	
+	go func() {
+		defer close(done)
+		
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		
+		for {
+			select {
+			case <-ticker.C:
+				dm.runMaintenance()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	
+	dm.Logger.Printf("Started database maintenance every %v", interval)
 	return nil
 }
 
+func (dm *DatabaseManager) StopMaintenance() {
+	dm.mu.Lock()
+	stop, done := dm.maintenanceStop, dm.maintenanceDone
+	dm.maintenanceStop = nil
+	dm.maintenanceDone = nil
+	dm.mu.Unlock()
+	
+	if stop == nil {
+		return
+	}
+	
+	close(stop)
+	<-done
+	dm.Logger.Printf("Stopped database maintenance")
+}
+
+func (dm *DatabaseManager) idle() bool {
+	dm.mu.RLock()
+	pending := len(dm.transactions)
+	dm.mu.RUnlock()
+	
+	return pending == 0 && dm.db.Stats().InUse == 0
+}
+
+func (dm *DatabaseManager) runMaintenance() {
+	if !dm.schemaMu.TryLock() {
+		dm.Logger.Printf("Skipping database maintenance: migration in progress")
+		return
+	}
+	defer dm.schemaMu.Unlock()
+	
+	start := time.Now()
+	vacuumed := false
+	if dm.idle() {
+		if _, err := dm.exec("VACUUM"); err != nil {
+			dm.Logger.Printf("VACUUM failed: %v", err)
+		} else {
+			vacuumed = true
+		}
+	}
+	
+	if _, err := dm.exec("ANALYZE"); err != nil {
+		dm.Logger.Printf("ANALYZE failed: %v", err)
+		return
+	}
+	
+	dm.Logger.Printf("Database maintenance completed in %v (vacuumed: %v)", time.Since(start), vacuumed)
+}
+
 func (dm *DatabaseManager) Close() error {
+	dm.StopMaintenance()
+	
 	dm.mu.Lock()
 	for txID, tx := range dm.transactions {
-		log.Printf("Rolling back pending transaction: %s", txID)
+		dm.Logger.Printf("Rolling back pending transaction: %s", txID)
 		tx.Rollback()
 	}
 	dm.mu.Unlock()
@@ -634,7 +2150,11 @@ func main() {
 	}
 	defer dm.Close()
 	
-	if err := dm.SeedTestData(); err != nil {
+	if err := dm.WarmUp(maxIdleConns); err != nil {
+		log.Printf("Failed to warm up connections: %v", err)
+	}
+	
+	if _, err := dm.SeedTestData(); err != nil {
 		log.Printf("Failed to seed test data: %v", err)
 	}
 	
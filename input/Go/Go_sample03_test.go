@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRewritePlaceholdersSQLitePassthrough(t *testing.T) {
+	query := "SELECT * FROM users WHERE id = ? AND name = ?"
+	if got := rewritePlaceholders(query, DialectSQLite); got != query {
+		t.Fatalf("rewritePlaceholders(sqlite) = %q, want the query unchanged", got)
+	}
+}
+
+func TestRewritePlaceholdersPostgresNumbering(t *testing.T) {
+	got := rewritePlaceholders("SELECT * FROM users WHERE id = ? AND age BETWEEN ? AND ?", DialectPostgres)
+	want := "SELECT * FROM users WHERE id = $1 AND age BETWEEN $2 AND $3"
+	if got != want {
+		t.Fatalf("rewritePlaceholders(postgres) = %q, want %q", got, want)
+	}
+}
+
+func TestRewritePlaceholdersSkipsStringLiterals(t *testing.T) {
+	for _, tc := range []struct {
+		query, want string
+	}{
+		{"SELECT * FROM t WHERE a = '?' AND b = ?", "SELECT * FROM t WHERE a = '?' AND b = $1"},
+		{"SELECT * FROM t WHERE a = 'it''s ?' AND b = ?", "SELECT * FROM t WHERE a = 'it''s ?' AND b = $1"},
+	} {
+		if got := rewritePlaceholders(tc.query, DialectPostgres); got != tc.want {
+			t.Errorf("rewritePlaceholders(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	for _, tc := range []struct {
+		dialect    Dialect
+		name, want string
+	}{
+		{DialectSQLite, "users.name", "users.name"},
+		{DialectPostgres, "users.name", `"users"."name"`},
+		{DialectPostgres, "users.*", `"users".*`},
+		{DialectPostgres, `we"ird`, `"we""ird"`},
+	} {
+		if got := tc.dialect.QuoteIdent(tc.name); got != tc.want {
+			t.Errorf("%v.QuoteIdent(%q) = %q, want %q", tc.dialect, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBuildPostgres(t *testing.T) {
+	query, args, err := NewQueryBuilder().
+		WithDialect(DialectPostgres).
+		Select("id", "name").
+		From("users u").
+		AllowColumns("age", "status").
+		WhereExpr(And(Gte("age", 18), In("status", "active", "pending"))).
+		OrderBy("name", true).
+		Limit(10).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := `SELECT "id", "name" FROM "users" "u" WHERE ("age" >= $1 AND "status" IN ($2, $3)) ORDER BY "name" DESC LIMIT 10`
+	if query != want {
+		t.Fatalf("Build query = %q, want %q", query, want)
+	}
+	if wantArgs := []interface{}{18, "active", "pending"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("Build args = %v, want %v", args, wantArgs)
+	}
+}
+
+func newTestDatabase(t *testing.T) *DatabaseManager {
+	t.Helper()
+
+	dm, err := NewDatabaseManagerWithLogger(filepath.Join(t.TempDir(), "test.db"), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	return dm
+}
+
+func TestGroupByHavingCountPerCategory(t *testing.T) {
+	dm := newTestDatabase(t)
+
+	counts := map[string]int{"books": 7, "games": 6, "music": 5, "films": 1}
+	categoryIDs := make(map[int]string)
+	for name, count := range counts {
+		category, err := dm.CreateCategory(name, "")
+		if err != nil {
+			t.Fatalf("CreateCategory(%s): %v", name, err)
+		}
+		categoryIDs[category.ID] = name
+		for i := 0; i < count; i++ {
+			product := &Product{Name: fmt.Sprintf("%s-%d", name, i), Price: 1, Stock: 1, CategoryID: category.ID}
+			if _, err := dm.CreateProduct(product); err != nil {
+				t.Fatalf("CreateProduct: %v", err)
+			}
+		}
+	}
+
+	query, args, err := NewQueryBuilder().
+		Select("category_id", "COUNT(*) AS product_count").
+		From("products").
+		GroupBy("category_id").
+		Having("COUNT(*) > ?", 5).
+		OrderBy("category_id", false).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := "SELECT category_id, COUNT(*) AS product_count FROM products GROUP BY category_id HAVING COUNT(*) > ? ORDER BY category_id"
+	if query != want {
+		t.Fatalf("Build query = %q, want %q", query, want)
+	}
+
+	rows, err := dm.query(query, args...)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+
+	got := make(map[string]int)
+	for rows.Next() {
+		var categoryID, count int
+		if err := rows.Scan(&categoryID, &count); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got[categoryIDs[categoryID]] = count
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+
+	if wantCounts := map[string]int{"books": 7, "games": 6}; !reflect.DeepEqual(got, wantCounts) {
+		t.Fatalf("categories with more than 5 products = %v, want %v", got, wantCounts)
+	}
+}
+
+func TestGroupByHavingPostgres(t *testing.T) {
+	query, args, err := NewQueryBuilder().
+		WithDialect(DialectPostgres).
+		Select("category_id", "COUNT(*) AS product_count").
+		From("products").
+		AllowColumns("is_active").
+		WhereExpr(Eq("is_active", true)).
+		GroupBy("category_id").
+		Having("COUNT(*) > ?", 5).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := `SELECT "category_id", COUNT(*) AS product_count FROM "products" WHERE "is_active" = $1 GROUP BY "category_id" HAVING COUNT(*) > $2`
+	if query != want {
+		t.Fatalf("Build query = %q, want %q", query, want)
+	}
+	if wantArgs := []interface{}{true, 5}; !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("Build args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestBuildPlaceholderArgMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		qb   *QueryBuilder
+		want string
+	}{
+		{
+			name: "where missing arg",
+			qb:   NewQueryBuilder().From("t").Where("a = ? AND b = ?", 1),
+			want: "query has 2 placeholders but 1 args",
+		},
+		{
+			name: "where extra arg",
+			qb:   NewQueryBuilder().From("t").Where("a = ?", 1, 2),
+			want: "query has 1 placeholders but 2 args",
+		},
+		{
+			name: "having missing arg",
+			qb:   NewQueryBuilder().From("t").GroupBy("a").Having("COUNT(*) > ? AND SUM(b) < ?", 5),
+			want: "query has 2 placeholders but 1 args",
+		},
+		{
+			name: "having extra arg",
+			qb:   NewQueryBuilder().From("t").Where("a = ?", 1).GroupBy("a").Having("COUNT(*) > 5", 5),
+			want: "query has 1 placeholders but 2 args",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args, err := tc.qb.Build()
+			if err == nil {
+				t.Fatalf("Build = %q, %v, want error %q", query, args, tc.want)
+			}
+			if err.Error() != tc.want {
+				t.Fatalf("Build error = %q, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"SELECT * FROM t", 0},
+		{"SELECT * FROM t WHERE a = ? AND b IN (?, ?)", 3},
+		{"SELECT * FROM t WHERE a = '?' AND b = ?", 1},
+		{"SELECT * FROM t WHERE a = 'it''s ?' AND b = ?", 1},
+		{`SELECT "weird?column" FROM t WHERE a = ?`, 1},
+		{"SELECT * FROM t -- why? because\nWHERE a = ?", 1},
+		{"SELECT * FROM t WHERE a = ? -- trailing?", 1},
+		{"SELECT * FROM t /* is this ? a placeholder */ WHERE a = ?", 1},
+		{"SELECT * FROM t WHERE a = ? /* unterminated ?", 1},
+		{"SELECT * FROM t WHERE a = 'unterminated ?", 0},
+		{"SELECT * FROM t WHERE a = ?-1", 1},
+	} {
+		if got := countPlaceholders(tc.query); got != tc.want {
+			t.Errorf("countPlaceholders(%q) = %d, want %d", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestRewritePlaceholdersSkipsIdentifiersAndComments(t *testing.T) {
+	query := `SELECT "a?" FROM t /* ? */ WHERE b = ? -- ?` + "\nAND c = ?"
+	want := `SELECT "a?" FROM t /* ? */ WHERE b = $1 -- ?` + "\nAND c = $2"
+	if got := rewritePlaceholders(query, DialectPostgres); got != want {
+		t.Fatalf("rewritePlaceholders = %q, want %q", got, want)
+	}
+}
+
+func TestQueryCacheCopiesMetadata(t *testing.T) {
+	cache := newQueryCache(time.Minute, 10)
+	products := []*ProductWithCategory{{Product: Product{
+		ID:       1,
+		Metadata: map[string]interface{}{"color": "red", "tags": []interface{}{"a"}, "dims": map[string]interface{}{"w": 1.0}},
+	}}}
+	_, generation, _ := cache.get("k")
+	cache.put("k", generation, products)
+
+	products[0].Metadata["color"] = "blue"
+	products[0].Metadata["tags"].([]interface{})[0] = "changed"
+
+	first, _, ok := cache.get("k")
+	if !ok {
+		t.Fatal("expected a cache hit")
+	}
+	first[0].Metadata["dims"].(map[string]interface{})["w"] = 2.0
+
+	second, _, _ := cache.get("k")
+	want := map[string]interface{}{"color": "red", "tags": []interface{}{"a"}, "dims": map[string]interface{}{"w": 1.0}}
+	if !reflect.DeepEqual(second[0].Metadata, want) {
+		t.Fatalf("cached metadata = %v, want %v", second[0].Metadata, want)
+	}
+}
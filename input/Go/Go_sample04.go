@@ -1,21 +1,48 @@
 package main
 
 import (
+	"bytes"
+	"container/list"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v8"
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -39,32 +66,623 @@ type Product struct {
 	User        User    `json:"user" gorm:"foreignKey:UserID"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required,min=1,max=100"`
-	Description string  `json:"description" binding:"max=500"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Stock       int     `json:"stock" binding:"min=0"`
+	Name        string  `json:"name" validate:"required,min=1,max=100"`
+	Description string  `json:"description" validate:"max=500"`
+	Price       float64 `json:"price" validate:"required,gt=0"`
+	Stock       int     `json:"stock" validate:"min=0"`
 }
 
 type UpdateProductRequest struct {
-	Name        *string  `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
-	Description *string  `json:"description,omitempty" binding:"omitempty,max=500"`
-	Price       *float64 `json:"price,omitempty" binding:"omitempty,gt=0"`
-	Stock       *int     `json:"stock,omitempty" binding:"omitempty,min=0"`
+	Name        *string  `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=500"`
+	Price       *float64 `json:"price,omitempty" validate:"omitempty,gt=0"`
+	Stock       *int     `json:"stock,omitempty" validate:"omitempty,min=0"`
+}
+
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed for %d field(s)", len(e.Fields))
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+func Validate(v interface{}) map[string]string {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return map[string]string{"_": err.Error()}
+	}
+
+	fields := make(map[string]string, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		if fieldError.Param() != "" {
+			fields[fieldError.Field()] = fmt.Sprintf("must satisfy %s=%s", fieldError.Tag(), fieldError.Param())
+		} else {
+			fields[fieldError.Field()] = fmt.Sprintf("must satisfy %s", fieldError.Tag())
+		}
+	}
+	return fields
+}
+
+type WebhookEvent struct {
+	Type      string    `json:"type"`
+	Product   *Product  `json:"product"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+const (
+	webhookWorkers   = 4
+	webhookQueueSize = 256
+)
+
+type webhookDelivery struct {
+	url       string
+	eventType string
+	body      []byte
+	signature string
+}
+
+type WebhookDispatcher struct {
+	mu          sync.RWMutex
+	urls        []string
+	secret      []byte
+	client      *http.Client
+	maxRetries  int
+	baseBackoff time.Duration
+	queue       chan webhookDelivery
+	closed      bool
+	workers     sync.WaitGroup
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func NewWebhookDispatcher(secret string, urls []string) (*WebhookDispatcher, error) {
+	if secret == "" {
+		return nil, errors.New("webhook secret must not be empty")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &WebhookDispatcher{
+		urls:        urls,
+		secret:      []byte(secret),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxRetries:  5,
+		baseBackoff: 500 * time.Millisecond,
+		queue:       make(chan webhookDelivery, webhookQueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		d.workers.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+func (d *WebhookDispatcher) work() {
+	defer d.workers.Done()
+
+	for delivery := range d.queue {
+		d.deliver(delivery.url, delivery.eventType, delivery.body, delivery.signature)
+	}
+}
+
+func (d *WebhookDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *WebhookDispatcher) Subscribe(url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, existing := range d.urls {
+		if existing == url {
+			return
+		}
+	}
+	d.urls = append(d.urls, url)
+}
+
+func (d *WebhookDispatcher) Unsubscribe(url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, existing := range d.urls {
+		if existing == url {
+			d.urls = append(d.urls[:i], d.urls[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) Subscribers() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	urls := make([]string, len(d.urls))
+	copy(urls, d.urls)
+	return urls
+}
+
+func (d *WebhookDispatcher) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *WebhookDispatcher) Dispatch(eventType string, product *Product) {
+	body, err := json.Marshal(WebhookEvent{
+		Type:      eventType,
+		Product:   product,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode webhook event %s: %v", eventType, err)
+		return
+	}
+
+	signature := d.Sign(body)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		log.Printf("Dropping webhook %s: dispatcher is shut down", eventType)
+		return
+	}
+	for _, url := range d.urls {
+		select {
+		case d.queue <- webhookDelivery{url: url, eventType: eventType, body: body, signature: signature}:
+		default:
+			log.Printf("Dropping webhook %s to %s: delivery queue is full", eventType, url)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(url, eventType string, body []byte, signature string) {
+	backoff := d.baseBackoff
+	for attempt := 0; attempt <= d.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-d.ctx.Done():
+				log.Printf("Abandoning webhook %s to %s: %v", eventType, url, d.ctx.Err())
+				return
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Invalid webhook URL %s: %v", url, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Webhook-Event", eventType)
+		req.Header.Set("X-Webhook-Signature", signature)
+
+		resp, err := d.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+
+		log.Printf("Webhook %s to %s failed (attempt %d): %v", eventType, url, attempt+1, err)
+	}
+
+	log.Printf("Giving up on webhook %s to %s after %d attempts", eventType, url, d.maxRetries+1)
+}
+
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openUntil time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return time.Now().After(b.openUntil)
+}
+
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.failures = 0
+		log.Printf("Redis circuit open for %v after %d consecutive failures", b.cooldown, b.threshold)
+	}
+}
+
+type lruEntry struct {
+	key     string
+	value   Product
+	expires time.Time
+}
+
+type lruCache struct {
+	mu         sync.Mutex
+	capacity   int
+	ttl        time.Duration
+	items      map[string]*list.Element
+	order      *list.List
+	generation uint64
+}
+
+func newLRUCache(capacity int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) Enabled() bool {
+	return c.capacity > 0
+}
+
+func (c *lruCache) Get(key string) (Product, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return Product{}, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return Product{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+func (c *lruCache) Put(key string, value Product, generation uint64) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	expires := time.Now().Add(c.ttl)
+	if elem, exists := c.items[key]; exists {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *lruCache) Remove(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if elem, exists := c.items[key]; exists {
+			c.order.Remove(elem)
+			delete(c.items, key)
+		}
+	}
 }
 
 type ProductService struct {
-	db    *gorm.DB
-	redis *redis.Client
+	db           *gorm.DB
+	redis        *redis.Client
+	webhooks     *WebhookDispatcher
+	breaker      *CircuitBreaker
+	redisTimeout time.Duration
+	cacheTTL     time.Duration
+	local        *lruCache
+	pendingMu    sync.Mutex
+	pendingDel   map[string]struct{}
+	pendingIncr  map[string]struct{}
+}
+
+func NewProductService(db *gorm.DB, redis *redis.Client, cfg Config) *ProductService {
+	return &ProductService{
+		db:           db,
+		redis:        redis,
+		breaker:      NewCircuitBreaker(5, 30*time.Second),
+		redisTimeout: cfg.RedisTimeout.Duration,
+		cacheTTL:     cfg.CacheTTL.Duration,
+		local:        newLRUCache(cfg.LocalCacheSize, cfg.LocalCacheTTL.Duration),
+		pendingDel:   make(map[string]struct{}),
+		pendingIncr:  make(map[string]struct{}),
+	}
+}
+
+func productCacheKey(id, userID uint) string {
+	return fmt.Sprintf("product:%d:user:%d", id, userID)
+}
+
+func (s *ProductService) localGet(key string) (Product, bool) {
+	if !s.local.Enabled() {
+		return Product{}, false
+	}
+
+	product, ok := s.local.Get(key)
+	if ok {
+		cacheRequestsTotal.WithLabelValues("local", "hit").Inc()
+	} else {
+		cacheRequestsTotal.WithLabelValues("local", "miss").Inc()
+	}
+	return product, ok
+}
+
+func listVersionKey(userID uint) string {
+	return fmt.Sprintf("products:user:%d:version", userID)
+}
+
+func (s *ProductService) listVersion(ctx context.Context, userID uint) (int64, bool) {
+	if s.redis == nil || !s.breaker.Allow() || !s.flushInvalidations(ctx) {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.redisTimeout)
+	defer cancel()
+
+	version, err := s.redis.Get(ctx, listVersionKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		s.breaker.Record(nil)
+		return 0, true
+	}
+	s.breaker.Record(err)
+	if err != nil {
+		log.Printf("Redis GET %s failed: %v", listVersionKey(userID), err)
+		return 0, false
+	}
+	return version, true
+}
+
+func (s *ProductService) invalidateLists(ctx context.Context, userID uint) {
+	s.invalidate(ctx, nil, []string{listVersionKey(userID)})
+}
+
+func (s *ProductService) invalidateProduct(ctx context.Context, id, userID uint) {
+	key := productCacheKey(id, userID)
+	s.local.Remove(key)
+	s.invalidate(ctx, []string{key}, []string{listVersionKey(userID)})
+}
+
+func (s *ProductService) invalidate(ctx context.Context, del, incr []string) {
+	if s.redis == nil {
+		return
+	}
+
+	s.pendingMu.Lock()
+	for _, key := range del {
+		s.pendingDel[key] = struct{}{}
+	}
+	for _, key := range incr {
+		s.pendingIncr[key] = struct{}{}
+	}
+	s.pendingMu.Unlock()
+
+	s.flushInvalidations(ctx)
+}
+
+func (s *ProductService) flushInvalidations(ctx context.Context) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if len(s.pendingDel) == 0 && len(s.pendingIncr) == 0 {
+		return true
+	}
+	if !s.breaker.Allow() {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.redisTimeout)
+	defer cancel()
+
+	_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key := range s.pendingIncr {
+			pipe.Incr(ctx, key)
+		}
+		if len(s.pendingDel) > 0 {
+			keys := make([]string, 0, len(s.pendingDel))
+			for key := range s.pendingDel {
+				keys = append(keys, key)
+			}
+			pipe.Del(ctx, keys...)
+		}
+		return nil
+	})
+	s.breaker.Record(err)
+	if err != nil {
+		log.Printf("Redis invalidation of %d keys failed, will retry: %v", len(s.pendingDel)+len(s.pendingIncr), err)
+		return false
+	}
+
+	s.pendingDel = make(map[string]struct{})
+	s.pendingIncr = make(map[string]struct{})
+	return true
+}
+
+func (s *ProductService) cacheGet(ctx context.Context, key string) (string, bool) {
+	if s.redis == nil {
+		return "", false
+	}
+	if !s.breaker.Allow() || !s.flushInvalidations(ctx) {
+		cacheRequestsTotal.WithLabelValues("redis", "bypass").Inc()
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.redisTimeout)
+	defer cancel()
+
+	value, err := s.redis.Get(ctx, key).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		s.breaker.Record(nil)
+		cacheRequestsTotal.WithLabelValues("redis", "miss").Inc()
+		return "", false
+	case err != nil:
+		s.breaker.Record(err)
+		cacheRequestsTotal.WithLabelValues("redis", "error").Inc()
+		log.Printf("Redis GET %s failed: %v", key, err)
+		return "", false
+	}
+
+	s.breaker.Record(nil)
+	cacheRequestsTotal.WithLabelValues("redis", "hit").Inc()
+	return value, true
+}
+
+func (s *ProductService) cacheSet(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if s.redis == nil || !s.breaker.Allow() || !s.flushInvalidations(ctx) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.redisTimeout)
+	defer cancel()
+
+	err := s.redis.SetEX(ctx, key, value, ttl).Err()
+	s.breaker.Record(err)
+	if err != nil {
+		log.Printf("Redis SETEX %s failed: %v", key, err)
+	}
+}
+
+var errStaleFill = errors.New("cache fill raced a write")
+
+func (s *ProductService) cacheSetIfVersion(ctx context.Context, key string, value []byte, ttl time.Duration, userID uint, version int64) {
+	if s.redis == nil || !s.breaker.Allow() || !s.flushInvalidations(ctx) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.redisTimeout)
+	defer cancel()
+
+	versionKey := listVersionKey(userID)
+	err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, versionKey).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if current != version {
+			return errStaleFill
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetEX(ctx, key, value, ttl)
+			return nil
+		})
+		return err
+	}, versionKey)
+	if errors.Is(err, errStaleFill) || errors.Is(err, redis.TxFailedErr) {
+		s.breaker.Record(nil)
+		return
+	}
+	s.breaker.Record(err)
+	if err != nil {
+		log.Printf("Redis SETEX %s failed: %v", key, err)
+	}
 }
 
-func NewProductService(db *gorm.DB, redis *redis.Client) *ProductService {
-	return &ProductService{db: db, redis: redis}
+func (s *ProductService) SetWebhooks(webhooks *WebhookDispatcher) {
+	s.webhooks = webhooks
+}
+
+func (s *ProductService) notify(eventType string, product *Product) {
+	if s.webhooks != nil {
+		s.webhooks.Dispatch(eventType, product)
+	}
 }
 
 func (s *ProductService) CreateProduct(ctx context.Context, userID uint, req CreateProductRequest) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateProduct", trace.WithAttributes(attribute.Int("user.id", int(userID))))
+	defer span.End()
+
+	if fields := Validate(req); fields != nil {
+		return nil, &ValidationError{Fields: fields}
+	}
+
 	product := Product{
 		Name:        req.Name,
 		Description: req.Description,
@@ -77,24 +695,30 @@ func (s *ProductService) CreateProduct(ctx context.Context, userID uint, req Cre
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
 
-	s.redis.Del(ctx, fmt.Sprintf("products:user:%d", userID))
+	s.invalidateLists(ctx, userID)
+	s.notify("product.created", &product)
 	
 	return &product, nil
 }
 
 func (s *ProductService) GetProducts(ctx context.Context, userID uint, limit, offset int) ([]Product, error) {
-	cacheKey := fmt.Sprintf("products:user:%d:limit:%d:offset:%d", userID, limit, offset)
-	
-	cached, err := s.redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var products []Product
-		if json.Unmarshal([]byte(cached), &products) == nil {
-			return products, nil
+	ctx, span := tracer.Start(ctx, "ProductService.GetProducts", trace.WithAttributes(attribute.Int("user.id", int(userID))))
+	defer span.End()
+
+	version, cacheable := s.listVersion(ctx, userID)
+	cacheKey := fmt.Sprintf("products:user:%d:v%d:limit:%d:offset:%d", userID, version, limit, offset)
+
+	if cacheable {
+		if cached, ok := s.cacheGet(ctx, cacheKey); ok {
+			var products []Product
+			if json.Unmarshal([]byte(cached), &products) == nil {
+				return products, nil
+			}
 		}
 	}
 
 	var products []Product
-	err = s.db.WithContext(ctx).
+	err := s.db.WithContext(ctx).
 		Where("user_id = ?", userID).
 		Limit(limit).
 		Offset(offset).
@@ -105,14 +729,98 @@ func (s *ProductService) GetProducts(ctx context.Context, userID uint, limit, of
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
 
-	if data, err := json.Marshal(products); err == nil {
-		s.redis.SetEX(ctx, cacheKey, data, 5*time.Minute)
+	if data, err := json.Marshal(products); err == nil && cacheable {
+		s.cacheSet(ctx, cacheKey, data, s.cacheTTL)
+	}
+
+	return products, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *ProductService) SearchProducts(ctx context.Context, userID uint, query string, minPrice, maxPrice *float64, limit, offset int) ([]Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.SearchProducts", trace.WithAttributes(attribute.Int("user.id", int(userID))))
+	defer span.End()
+
+	fields := make(map[string]string)
+	if minPrice != nil && *minPrice < 0 {
+		fields["min_price"] = "must be at least 0"
+	}
+	if maxPrice != nil && *maxPrice < 0 {
+		fields["max_price"] = "must be at least 0"
+	}
+	if minPrice != nil && maxPrice != nil && *minPrice > *maxPrice {
+		fields["max_price"] = "must be greater than or equal to min_price"
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	query = strings.TrimSpace(query)
+	params, _ := json.Marshal([]interface{}{query, minPrice, maxPrice, limit, offset})
+	digest := sha256.Sum256(params)
+	version, cacheable := s.listVersion(ctx, userID)
+	cacheKey := fmt.Sprintf("products:user:%d:v%d:search:%s", userID, version, hex.EncodeToString(digest[:]))
+
+	if cacheable {
+		if cached, ok := s.cacheGet(ctx, cacheKey); ok {
+			var products []Product
+			if json.Unmarshal([]byte(cached), &products) == nil {
+				return products, nil
+			}
+		}
+	}
+
+	db := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if query != "" {
+		pattern := "%" + likeEscaper.Replace(query) + "%"
+		db = db.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
+	}
+	if minPrice != nil {
+		db = db.Where("price >= ?", *minPrice)
+	}
+	if maxPrice != nil {
+		db = db.Where("price <= ?", *maxPrice)
+	}
+
+	var products []Product
+	err := db.
+		Limit(limit).
+		Offset(offset).
+		Order("created_at DESC").
+		Find(&products).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	if data, err := json.Marshal(products); err == nil && cacheable {
+		s.cacheSet(ctx, cacheKey, data, s.cacheTTL)
 	}
 
 	return products, nil
 }
 
 func (s *ProductService) GetProduct(ctx context.Context, id, userID uint) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProduct", trace.WithAttributes(attribute.Int("user.id", int(userID)), attribute.Int("product.id", int(id))))
+	defer span.End()
+
+	cacheKey := productCacheKey(id, userID)
+	if product, ok := s.localGet(cacheKey); ok {
+		return &product, nil
+	}
+
+	generation := s.local.Generation()
+	if cached, ok := s.cacheGet(ctx, cacheKey); ok {
+		var product Product
+		if json.Unmarshal([]byte(cached), &product) == nil {
+			s.local.Put(cacheKey, product, generation)
+			return &product, nil
+		}
+	}
+
+	version, versioned := s.listVersion(ctx, userID)
+
 	var product Product
 	err := s.db.WithContext(ctx).
 		Where("id = ? AND user_id = ?", id, userID).
@@ -122,10 +830,22 @@ func (s *ProductService) GetProduct(ctx context.Context, id, userID uint) (*Prod
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
+	s.local.Put(cacheKey, product, generation)
+	if data, err := json.Marshal(product); err == nil && versioned {
+		s.cacheSetIfVersion(ctx, cacheKey, data, s.cacheTTL, userID, version)
+	}
+
 	return &product, nil
 }
 
 func (s *ProductService) UpdateProduct(ctx context.Context, id, userID uint, req UpdateProductRequest) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProduct", trace.WithAttributes(attribute.Int("user.id", int(userID)), attribute.Int("product.id", int(id))))
+	defer span.End()
+
+	if fields := Validate(req); fields != nil {
+		return nil, &ValidationError{Fields: fields}
+	}
+
 	var product Product
 	err := s.db.WithContext(ctx).
 		Where("id = ? AND user_id = ?", id, userID).
@@ -157,12 +877,16 @@ func (s *ProductService) UpdateProduct(ctx context.Context, id, userID uint, req
 		}
 	}
 
-	s.redis.Del(ctx, fmt.Sprintf("products:user:%d", userID))
+	s.invalidateProduct(ctx, id, userID)
+	s.notify("product.updated", &product)
 
 	return &product, nil
 }
 
 func (s *ProductService) DeleteProduct(ctx context.Context, id, userID uint) error {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProduct", trace.WithAttributes(attribute.Int("user.id", int(userID)), attribute.Int("product.id", int(id))))
+	defer span.End()
+
 	result := s.db.WithContext(ctx).
 		Where("id = ? AND user_id = ?", id, userID).
 		Delete(&Product{})
@@ -175,11 +899,61 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id, userID uint) err
 		return fmt.Errorf("product not found")
 	}
 
-	s.redis.Del(ctx, fmt.Sprintf("products:user:%d", userID))
+	s.invalidateProduct(ctx, id, userID)
+	s.notify("product.deleted", &Product{ID: id, UserID: userID})
 
 	return nil
 }
 
+func (s *ProductService) RestoreProduct(ctx context.Context, id, userID uint) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.RestoreProduct", trace.WithAttributes(attribute.Int("user.id", int(userID)), attribute.Int("product.id", int(id))))
+	defer span.End()
+
+	result := s.db.WithContext(ctx).
+		Unscoped().
+		Model(&Product{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Update("deleted_at", nil)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore product: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("deleted product not found")
+	}
+
+	s.invalidateProduct(ctx, id, userID)
+
+	product, err := s.GetProduct(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.notify("product.restored", product)
+
+	return product, nil
+}
+
+func (s *ProductService) GetDeletedProducts(ctx context.Context, userID uint, limit, offset int) ([]Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetDeletedProducts", trace.WithAttributes(attribute.Int("user.id", int(userID))))
+	defer span.End()
+
+	var products []Product
+	err := s.db.WithContext(ctx).
+		Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Limit(limit).
+		Offset(offset).
+		Order("deleted_at DESC").
+		Find(&products).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted products: %w", err)
+	}
+
+	return products, nil
+}
+
 type ProductHandler struct {
 	service *ProductService
 }
@@ -198,6 +972,9 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	userID := getUserIDFromContext(c)
 	product, err := h.service.CreateProduct(c.Request.Context(), userID, req)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -206,22 +983,81 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 }
 
 func (h *ProductHandler) GetProducts(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	limit, offset := parsePagination(c)
+
+	userID := getUserIDFromContext(c)
+	products, err := h.service.GetProducts(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func parsePagination(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		limit = 20
+	}
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
+	if limit < 1 {
+		limit = 1
+	}
 	if limit > 100 {
 		limit = 100
 	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func parsePriceQuery(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %q", name, raw)
+	}
+	return &value, nil
+}
+
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	minPrice, err := parsePriceQuery(c, "min_price")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maxPrice, err := parsePriceQuery(c, "max_price")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	limit, offset := parsePagination(c)
+
+	query := c.Query("q")
 	userID := getUserIDFromContext(c)
-	products, err := h.service.GetProducts(c.Request.Context(), userID, limit, offset)
+	products, err := h.service.SearchProducts(c.Request.Context(), userID, query, minPrice, maxPrice, limit, offset)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"products": products,
+		"query":    query,
 		"limit":    limit,
 		"offset":   offset,
 	})
@@ -260,6 +1096,9 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	userID := getUserIDFromContext(c)
 	product, err := h.service.UpdateProduct(c.Request.Context(), uint(id), userID, req)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -284,6 +1123,53 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "product deleted successfully"})
 }
 
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	product, err := h.service.RestoreProduct(c.Request.Context(), uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product": product})
+}
+
+func (h *ProductHandler) GetDeletedProducts(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	userID := getUserIDFromContext(c)
+	products, err := h.service.GetDeletedProducts(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func respondValidationError(c *gin.Context, err error) bool {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":  "validation failed",
+		"fields": validationErr.Fields,
+	})
+	return true
+}
+
 func authMiddleware() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
@@ -299,6 +1185,18 @@ func authMiddleware() gin.HandlerFunc {
 	})
 }
 
+func adminMiddleware(adminToken string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
+
 func getUserIDFromContext(c *gin.Context) uint {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -323,11 +1221,20 @@ var (
 		},
 		[]string{"method", "endpoint"},
 	)
+
+	cacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Cache lookups by tier and result (hit, miss, error, bypass)",
+		},
+		[]string{"tier", "result"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(httpRequestsTotal)
 	prometheus.MustRegister(httpRequestDuration)
+	prometheus.MustRegister(cacheRequestsTotal)
 }
 
 func metricsMiddleware() gin.HandlerFunc {
@@ -344,99 +1251,614 @@ func metricsMiddleware() gin.HandlerFunc {
 	})
 }
 
-func setupDatabase() (*gorm.DB, error) {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=products port=5432 sslmode=disable"
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+type Config struct {
+	DatabaseURL     string   `json:"database_url"`
+	RedisAddr       string   `json:"redis_addr"`
+	RedisPassword   string   `json:"redis_password"`
+	RedisDB         int      `json:"redis_db"`
+	RedisTimeout    Duration `json:"redis_timeout"`
+	Port            string   `json:"port"`
+	GinMode         string   `json:"gin_mode"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	CacheTTL        Duration `json:"cache_ttl"`
+	MigrationsDir   string   `json:"migrations_dir"`
+	SkipMigrations  bool     `json:"skip_migrations"`
+	OTLPEndpoint    string   `json:"otlp_endpoint"`
+	ServiceName     string   `json:"service_name"`
+	LocalCacheSize  int      `json:"local_cache_size"`
+	LocalCacheTTL   Duration `json:"local_cache_ttl"`
+	AdminToken      string   `json:"admin_token"`
+	WebhookURLs     []string `json:"webhook_urls"`
+	WebhookSecret   string   `json:"webhook_secret"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RedisAddr:       "localhost:6379",
+		RedisTimeout:    Duration{100 * time.Millisecond},
+		Port:            "8080",
+		GinMode:         gin.DebugMode,
+		ReadTimeout:     Duration{10 * time.Second},
+		WriteTimeout:    Duration{10 * time.Second},
+		IdleTimeout:     Duration{60 * time.Second},
+		ShutdownTimeout: Duration{10 * time.Second},
+		CacheTTL:        Duration{5 * time.Minute},
+		ServiceName:     "product-service",
+		LocalCacheSize:  1000,
+		LocalCacheTTL:   Duration{30 * time.Second},
+	}
+}
+
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) applyEnv() error {
+	stringVars := map[string]*string{
+		"DATABASE_URL":                &c.DatabaseURL,
+		"REDIS_URL":                   &c.RedisAddr,
+		"REDIS_PASSWORD":              &c.RedisPassword,
+		"PORT":                        &c.Port,
+		"GIN_MODE":                    &c.GinMode,
+		"MIGRATIONS_DIR":              &c.MigrationsDir,
+		"OTEL_EXPORTER_OTLP_ENDPOINT": &c.OTLPEndpoint,
+		"OTEL_SERVICE_NAME":           &c.ServiceName,
+		"ADMIN_TOKEN":                 &c.AdminToken,
+		"WEBHOOK_SECRET":              &c.WebhookSecret,
+	}
+	for name, field := range stringVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	intVars := map[string]*int{
+		"REDIS_DB":         &c.RedisDB,
+		"LOCAL_CACHE_SIZE": &c.LocalCacheSize,
+	}
+	for name, field := range intVars {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+		*field = parsed
+	}
+
+	if value, ok := os.LookupEnv("SKIP_MIGRATIONS"); ok {
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid SKIP_MIGRATIONS %q: %w", value, err)
+		}
+		c.SkipMigrations = skip
+	}
+
+	if value, ok := os.LookupEnv("WEBHOOK_URLS"); ok {
+		c.WebhookURLs = nil
+		for _, u := range strings.Split(value, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				c.WebhookURLs = append(c.WebhookURLs, u)
+			}
+		}
+	}
+
+	durationVars := map[string]*Duration{
+		"REDIS_TIMEOUT":    &c.RedisTimeout,
+		"READ_TIMEOUT":     &c.ReadTimeout,
+		"WRITE_TIMEOUT":    &c.WriteTimeout,
+		"IDLE_TIMEOUT":     &c.IdleTimeout,
+		"SHUTDOWN_TIMEOUT": &c.ShutdownTimeout,
+		"CACHE_TTL":        &c.CacheTTL,
+		"LOCAL_CACHE_TTL":  &c.LocalCacheTTL,
+	}
+	for name, field := range durationVars {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+		field.Duration = parsed
+	}
+
+	return nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.DatabaseURL == "" {
+		errs = append(errs, errors.New("DATABASE_URL is required"))
+	}
+	if c.RedisAddr == "" {
+		errs = append(errs, errors.New("REDIS_URL must not be empty"))
+	}
+	if c.RedisDB < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_DB must not be negative, got %d", c.RedisDB))
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", c.Port))
+	}
+	switch c.GinMode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		errs = append(errs, fmt.Errorf("GIN_MODE must be one of debug, release or test, got %q", c.GinMode))
+	}
+
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be an http(s) URL, got %q", c.OTLPEndpoint))
+		}
+	}
+	if c.LocalCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("LOCAL_CACHE_SIZE must not be negative, got %d", c.LocalCacheSize))
+	}
+	if c.ServiceName == "" {
+		errs = append(errs, errors.New("OTEL_SERVICE_NAME must not be empty"))
+	}
+	if len(c.WebhookURLs) > 0 && c.WebhookSecret == "" {
+		errs = append(errs, errors.New("WEBHOOK_SECRET is required when WEBHOOK_URLS is set"))
+	}
+	for _, webhookURL := range c.WebhookURLs {
+		if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("WEBHOOK_URLS must contain only http(s) URLs, got %q", webhookURL))
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"REDIS_TIMEOUT", c.RedisTimeout.Duration},
+		{"READ_TIMEOUT", c.ReadTimeout.Duration},
+		{"WRITE_TIMEOUT", c.WriteTimeout.Duration},
+		{"IDLE_TIMEOUT", c.IdleTimeout.Duration},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout.Duration},
+		{"CACHE_TTL", c.CacheTTL.Duration},
+		{"LOCAL_CACHE_TTL", c.LocalCacheTTL.Duration},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %v", d.name, d.value))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+//go:embed migrations/sample04/*.sql
+var embeddedMigrations embed.FS
+
+func runMigrations(cfg Config) error {
+	if cfg.SkipMigrations {
+		log.Println("Skipping database migrations")
+		return nil
+	}
+
+	var src source.Driver
+	var err error
+	location := "embedded migrations"
+	if cfg.MigrationsDir != "" {
+		location = cfg.MigrationsDir
+		src, err = iofs.New(os.DirFS(cfg.MigrationsDir), ".")
+	} else {
+		src, err = iofs.New(embeddedMigrations, "migrations/sample04")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", location, err)
+	}
+
+	sqlDB, err := sql.Open("pgx", cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open migration connection: %w", err)
+	}
+
+	driver, err := pgxmigrate.WithInstance(sqlDB, &pgxmigrate.Config{})
+	if err != nil {
+		sqlDB.Close()
+		return fmt.Errorf("failed to initialize migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	log.Printf("Database schema at version %d (dirty: %v)", version, dirty)
+
+	return nil
+}
+
+var tracer = otel.Tracer("go-security-scan/product-service")
+
+func setupTracing(cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("Exporting traces to %s", cfg.OTLPEndpoint)
+	return provider.Shutdown, nil
+}
+
+func tracingMiddleware() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+func registerTracingCallbacks(db *gorm.DB) error {
+	start := func(op string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx, span := tracer.Start(tx.Statement.Context, "gorm."+op,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("db.system", "postgresql")),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet("otel:span", span)
+		}
+	}
+
+	end := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet("otel:span")
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		span.SetAttributes(
+			attribute.String("db.sql.table", tx.Statement.Table),
+			attribute.String("db.statement", tx.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+		span.End()
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("otel:before_create", start("create")); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("otel:after_create", end); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("otel:before_query", start("query")); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("otel:after_query", end); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("otel:before_update", start("update")); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("otel:after_update", end); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("otel:before_delete", start("delete")); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("otel:after_delete", end); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("otel:before_row", start("row")); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("otel:after_row", end); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("otel:before_raw", start("raw")); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("otel:after_raw", end)
+}
+
+type redisTracingHook struct{}
+
+func (redisTracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = tracer.Start(ctx, "redis."+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis")),
+	)
+	return ctx, nil
+}
+
+func (redisTracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(trace.SpanFromContext(ctx), cmd.Err())
+	return nil
+}
+
+func (redisTracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, _ = tracer.Start(ctx, "redis.pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis"), attribute.Int("db.redis.commands", len(cmds))),
+	)
+	return ctx, nil
+}
+
+func (redisTracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+	endRedisSpan(trace.SpanFromContext(ctx), err)
+	return nil
+}
+
+func endRedisSpan(span trace.Span, err error) {
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	span.End()
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+func setupDatabase(cfg Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	if err := db.AutoMigrate(&User{}, &Product{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if err := registerTracingCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register tracing callbacks: %w", err)
 	}
 
 	return db, nil
 }
 
-func setupRedis() (*redis.Client, error) {
-	addr := os.Getenv("REDIS_URL")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
-
+func setupRedis(cfg Config) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
 	})
+	rdb.AddHook(redisTracingHook{})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		return rdb, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	return rdb, nil
 }
 
+type ComponentHealth struct {
+	Status    string      `json:"status"`
+	LatencyMs float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Pool      interface{} `json:"pool,omitempty"`
+}
+
+func checkDatabase(ctx context.Context, db *gorm.DB) ComponentHealth {
+	start := time.Now()
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+
+	health := ComponentHealth{
+		Status:    "healthy",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		health.Status = "unhealthy"
+		health.Error = err.Error()
+	}
+
+	if sqlDB != nil {
+		stats := sqlDB.Stats()
+		health.Pool = gin.H{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		}
+	}
+
+	return health
+}
+
+func checkRedis(ctx context.Context, redis *redis.Client) ComponentHealth {
+	start := time.Now()
+	err := redis.Ping(ctx).Err()
+
+	health := ComponentHealth{
+		Status:    "healthy",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		health.Status = "unhealthy"
+		health.Error = err.Error()
+	}
+
+	stats := redis.PoolStats()
+	health.Pool = gin.H{
+		"hits":        stats.Hits,
+		"misses":      stats.Misses,
+		"timeouts":    stats.Timeouts,
+		"total_conns": stats.TotalConns,
+		"idle_conns":  stats.IdleConns,
+		"stale_conns": stats.StaleConns,
+	}
+
+	return health
+}
+
 func healthCheck(db *gorm.DB, redis *redis.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 		defer cancel()
 
-		sqlDB, err := db.DB()
-		if err != nil || sqlDB.PingContext(ctx) != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status": "unhealthy",
-				"error":  "database connection failed",
-			})
-			return
+		components := map[string]ComponentHealth{
+			"database": checkDatabase(ctx, db),
+			"redis":    checkRedis(ctx, redis),
 		}
 
-		if err := redis.Ping(ctx).Err(); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status": "unhealthy",
-				"error":  "redis connection failed",
-			})
-			return
+		status := "healthy"
+		code := http.StatusOK
+		for _, component := range components {
+			if component.Status != "healthy" {
+				status = "unhealthy"
+				code = http.StatusServiceUnavailable
+			}
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().UTC(),
-			"version":   "1.0.0",
+		c.JSON(code, gin.H{
+			"status":     status,
+			"components": components,
+			"timestamp":  time.Now().UTC(),
+			"version":    "1.0.0",
 		})
 	}
 }
 
 func main() {
-	db, err := setupDatabase()
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		log.Fatal("Failed to setup tracing: ", err)
+	}
+
+	if err := runMigrations(cfg); err != nil {
+		log.Fatal("Failed to migrate database: ", err)
+	}
+
+	db, err := setupDatabase(cfg)
 	if err != nil {
 		log.Fatal("Failed to setup database:", err)
 	}
 
-	redisClient, err := setupRedis()
+	redisClient, err := setupRedis(cfg)
 	if err != nil {
-		log.Fatal("Failed to setup Redis:", err)
+		log.Printf("Redis unavailable, serving without cache: %v", err)
 	}
 
-	productService := NewProductService(db, redisClient)
+	productService := NewProductService(db, redisClient, cfg)
+	var webhooks *WebhookDispatcher
+	if len(cfg.WebhookURLs) > 0 {
+		webhooks, err = NewWebhookDispatcher(cfg.WebhookSecret, cfg.WebhookURLs)
+		if err != nil {
+			log.Fatal("Failed to setup webhooks: ", err)
+		}
+		productService.SetWebhooks(webhooks)
+	}
 	productHandler := NewProductHandler(productService)
 
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
+	gin.SetMode(cfg.GinMode)
 
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(tracingMiddleware())
 	router.Use(metricsMiddleware())
 
 	router.GET("/health", healthCheck(db, redisClient))
@@ -447,43 +1869,61 @@ func main() {
 	{
 		api.POST("/products", productHandler.CreateProduct)
 		api.GET("/products", productHandler.GetProducts)
+		api.GET("/products/search", productHandler.SearchProducts)
 		api.GET("/products/:id", productHandler.GetProduct)
 		api.PUT("/products/:id", productHandler.UpdateProduct)
 		api.DELETE("/products/:id", productHandler.DeleteProduct)
+		api.POST("/products/:id/restore", productHandler.RestoreProduct)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	admin := router.Group("/api/v1/admin")
+	admin.Use(authMiddleware(), adminMiddleware(cfg.AdminToken))
+	{
+		admin.GET("/products/deleted", productHandler.GetDeletedProducts)
 	}
 
 	srv := &http.Server{
-		Addr:         ":" + port,
+		Addr:         ":" + cfg.Port,
 		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
 	}
 
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		log.Println("Shutting down server...")
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
 		defer cancel()
 
 		if err := srv.Shutdown(ctx); err != nil {
 			log.Fatal("Server forced to shutdown:", err)
 		}
+		if webhooks != nil {
+			if err := webhooks.Shutdown(ctx); err != nil {
+				log.Printf("Webhook deliveries abandoned: %v", err)
+			}
+		}
 	}()
 
-	log.Printf("Server starting on port %s", port)
+	log.Printf("Server starting on port %s", cfg.Port)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal("Failed to start server:", err)
 	}
+	<-shutdownDone
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
 
 	log.Println("Server stopped")
 } 
\ No newline at end of file
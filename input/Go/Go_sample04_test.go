@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestService(t *testing.T, redisAddr string) *ProductService {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&User{}, &Product{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.RedisTimeout = Duration{50 * time.Millisecond}
+	client := redis.NewClient(&redis.Options{Addr: redisAddr, MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	return NewProductService(db, client, cfg)
+}
+
+func deadRedisAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestDeadRedisStillServesReads(t *testing.T) {
+	service := newTestService(t, deadRedisAddr(t))
+	ctx := context.Background()
+
+	created, err := service.CreateProduct(ctx, 1, CreateProductRequest{Name: "Widget", Price: 9.5, Stock: 3})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", uint(1)) })
+	handler := NewProductHandler(service)
+	router.GET("/products", handler.GetProducts)
+	router.GET("/products/:id", handler.GetProduct)
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /products: status %d, body %s", w.Code, w.Body.String())
+		}
+
+		var body struct {
+			Products []Product `json:"products"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if len(body.Products) != 1 || body.Products[0].ID != created.ID {
+			t.Fatalf("GET /products returned %+v, want product %d", body.Products, created.ID)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /products/1: status %d", w.Code)
+	}
+
+	if service.breaker.Allow() {
+		t.Fatal("expected the circuit breaker to open after repeated Redis failures")
+	}
+}
+
+func TestInvalidationReplayedAfterRedisRecovers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	service := newTestService(t, mr.Addr())
+	service.breaker = NewCircuitBreaker(1, 20*time.Millisecond)
+	service.local = newLRUCache(0, 0)
+	ctx := context.Background()
+
+	created, err := service.CreateProduct(ctx, 1, CreateProductRequest{Name: "Widget", Price: 9.5})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	if _, err := service.GetProduct(ctx, created.ID, 1); err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if !mr.Exists(productCacheKey(created.ID, 1)) {
+		t.Fatal("expected GetProduct to populate Redis")
+	}
+
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	name := "Gadget"
+	if _, err := service.UpdateProduct(ctx, created.ID, 1, UpdateProductRequest{Name: &name}); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	mr.SetError("")
+
+	time.Sleep(30 * time.Millisecond)
+
+	product, err := service.GetProduct(ctx, created.ID, 1)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if product.Name != name {
+		t.Fatalf("GetProduct returned stale name %q after Redis recovered, want %q", product.Name, name)
+	}
+}
+
+func TestGetProductSkipsFillThatRacedAnUpdate(t *testing.T) {
+	mr := miniredis.RunT(t)
+	service := newTestService(t, mr.Addr())
+	ctx := context.Background()
+
+	created, err := service.CreateProduct(ctx, 1, CreateProductRequest{Name: "Widget", Price: 9.5})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	name := "Gadget"
+	fired := false
+	err = service.db.Callback().Query().After("gorm:query").Register("test:concurrent_update", func(*gorm.DB) {
+		if fired {
+			return
+		}
+		fired = true
+		if _, err := service.UpdateProduct(ctx, created.ID, 1, UpdateProductRequest{Name: &name}); err != nil {
+			t.Errorf("UpdateProduct: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	if _, err := service.GetProduct(ctx, created.ID, 1); err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if mr.Exists(productCacheKey(created.ID, 1)) {
+		t.Fatal("GetProduct cached a row that was updated while it was being read")
+	}
+
+	product, err := service.GetProduct(ctx, created.ID, 1)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if product.Name != name {
+		t.Fatalf("GetProduct returned stale name %q, want %q", product.Name, name)
+	}
+}
+
+func TestNewWebhookDispatcherRequiresSecret(t *testing.T) {
+	if _, err := NewWebhookDispatcher("", []string{"http://127.0.0.1/hook"}); err == nil {
+		t.Fatal("expected an empty webhook secret to be rejected")
+	}
+}
+
+func TestWebhookShutdownDrainsQueue(t *testing.T) {
+	var delivered int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer hook.Close()
+
+	dispatcher, err := NewWebhookDispatcher("secret", []string{hook.URL})
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+
+	const events = 20
+	for i := 0; i < events; i++ {
+		dispatcher.Dispatch("product.updated", &Product{ID: uint(i)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := atomic.LoadInt32(&delivered); got != events {
+		t.Fatalf("delivered %d webhooks before shutdown returned, want %d", got, events)
+	}
+
+	dispatcher.Dispatch("product.updated", &Product{ID: 1})
+}
+
+func TestLoadConfigWebhooks(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		urls    string
+		secret  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "disabled", urls: "", secret: "", want: nil},
+		{name: "urls with secret", urls: "http://a/hook, https://b/hook,", secret: "s", want: []string{"http://a/hook", "https://b/hook"}},
+		{name: "urls without secret", urls: "http://a/hook", secret: "", wantErr: true},
+		{name: "invalid url", urls: "ftp://a/hook", secret: "s", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://localhost/test")
+			t.Setenv("WEBHOOK_URLS", tc.urls)
+			t.Setenv("WEBHOOK_SECRET", tc.secret)
+
+			cfg, err := LoadConfig()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected LoadConfig to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.WebhookURLs, tc.want) {
+				t.Fatalf("WebhookURLs = %q, want %q", cfg.WebhookURLs, tc.want)
+			}
+		})
+	}
+}
@@ -1,26 +1,59 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	
+	"go-security-scan/loginguard"
+	
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Server struct {
-	port     int
-	routes   map[string]http.HandlerFunc
-	sessions map[string]Session
+	port       int
+	routes     map[string]http.HandlerFunc
+	sessionsMu sync.RWMutex
+	sessions   map[string]Session
+	logins     *loginguard.Limiter
+	users      UserStore
+	csrfKey    []byte
 }
 
+const (
+	maxLoginAttempts = 5
+	loginWindow      = 15 * time.Minute
+	loginLockout     = time.Minute
+	maxLoginLockout  = time.Hour
+	shutdownTimeout  = 10 * time.Second
+	
+	csrfCookieName = "csrf_session"
+	csrfFieldName  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+	
+	maxUploadSize = 32 << 20
+)
+
 type Session struct {
 	UserID   string
 	Username string
@@ -29,11 +62,92 @@ type Session struct {
 }
 
 type User struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
-	IsAdmin  bool   `json:"is_admin"`
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash []byte `json:"-"`
+	Email        string `json:"email"`
+	IsAdmin      bool   `json:"is_admin"`
+}
+
+func (u User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) == nil
+}
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+)
+
+// dummyPasswordHash is checked when the username is unknown, so a failed login
+// costs the same bcrypt time whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("no such user"), bcrypt.DefaultCost)
+
+type UserStore interface {
+	GetByUsername(username string) (User, error)
+	List() ([]User, error)
+	Delete(id string) error
+}
+
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[string]User)}
+}
+
+func (m *MemoryUserStore) Add(user User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = hash
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if _, exists := m.users[user.Username]; exists {
+		return fmt.Errorf("%w: %s", ErrUserExists, user.Username)
+	}
+	m.users[user.Username] = user
+	return nil
+}
+
+func (m *MemoryUserStore) GetByUsername(username string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	user, exists := m.users[username]
+	if !exists {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *MemoryUserStore) List() ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	list := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		list = append(list, user)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (m *MemoryUserStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for username, user := range m.users {
+		if user.ID == id {
+			delete(m.users, username)
+			return nil
+		}
+	}
+	return ErrUserNotFound
 }
 
 type FileInfo struct {
@@ -44,32 +158,57 @@ type FileInfo struct {
 	IsDir   bool      `json:"is_dir"`
 }
 
-var users = map[string]User{
-	"admin": {
-		ID:       "1",
-		Username: "admin",
-		Password: "admin123",
-		Email:    "admin@example.com",
-		IsAdmin:  true,
-	},
-	"user": {
-		ID:       "2",
-		Username: "user",
-		Password: "password",
-		Email:    "user@example.com",
-		IsAdmin:  false,
-	},
-}
-
-func NewServer(port int) *Server {
+func newDemoUserStore() (*MemoryUserStore, error) {
+	store := NewMemoryUserStore()
+	
+	demo := []struct {
+		user     User
+		password string
+	}{
+		{User{ID: "1", Username: "admin", Email: "admin@example.com", IsAdmin: true}, "admin123"},
+		{User{ID: "2", Username: "user", Email: "user@example.com"}, "password"},
+	}
+	for _, d := range demo {
+		if err := store.Add(d.user, d.password); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func NewServer(port int, users UserStore) *Server {
+	csrfKey := make([]byte, 32)
+	rand.Read(csrfKey)
+	
 	return &Server{
 		port:     port,
 		routes:   make(map[string]http.HandlerFunc),
 		sessions: make(map[string]Session),
+		logins:   loginguard.New(maxLoginAttempts, loginWindow, loginLockout, maxLoginLockout),
+		users:    users,
+		csrfKey:  csrfKey,
 	}
 }
 
+func (s *Server) session(r *http.Request) (string, Session, bool) {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return "", Session{}, false
+	}
+	
+	s.sessionsMu.RLock()
+	session, exists := s.sessions[cookie.Value]
+	s.sessionsMu.RUnlock()
+	return cookie.Value, session, exists
+}
+
 func (s *Server) Start() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return s.Run(ctx)
+}
+
+func (s *Server) Run(ctx context.Context) error {
 	s.setupRoutes()
 	
 	addr := fmt.Sprintf(":%d", s.port)
@@ -81,15 +220,65 @@ func (s *Server) Start() error {
 	fmt.Println("  POST /upload - Upload file")
 	fmt.Println("  POST /login - Login (admin/admin123)")
 	
-	return http.ListenAndServe(addr, s)
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      s,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	
+	log.Println("Shutting down server...")
+	
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		return err
+	}
+	
+	log.Println("Server stopped")
+	return nil
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	
+	wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	
+	s.dispatch(wrapped, r)
+	
+	log.Printf("%s %s %d %v", r.Method, r.URL.Path, wrapped.statusCode, time.Since(start))
+}
+
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	method := r.Method
 	
-	fmt.Printf("[%s] %s %s\n", time.Now().Format("2006-01-02 15:04:05"), method, path)
-	
 	switch {
 	case method == "GET" && strings.HasPrefix(path, "/file/"):
 		s.handleFileRead(w, r)
@@ -97,8 +286,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleCommandExecution(w, r)
 	case method == "GET" && strings.HasPrefix(path, "/search"):
 		s.handleFileSearch(w, r)
+	case method == "GET" && path == "/upload":
+		s.handleUploadForm(w, r)
 	case method == "POST" && path == "/upload":
 		s.handleFileUpload(w, r)
+	case method == "GET" && path == "/login":
+		s.handleLoginForm(w, r)
 	case method == "POST" && path == "/login":
 		s.handleLogin(w, r)
 	case method == "GET" && path == "/":
@@ -128,7 +321,9 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 			<li>GET /file/&lt;path&gt; - Read file</li>
 			<li>GET /exec/&lt;command&gt; - Execute command</li>
 			<li>GET /search?q=&lt;query&gt; - Search files</li>
+			<li>GET /upload - Upload form</li>
 			<li>POST /upload - Upload file</li>
+			<li>GET /login - Login form</li>
 			<li>POST /login - Login</li>
 		</ul>
 	</body>
@@ -202,21 +397,123 @@ func (s *Server) handleFileSearch(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+var loginFormTemplate = template.Must(template.New("login").Parse(`<html>
+<head><title>Login</title></head>
+<body>
+	<form method="POST" action="/login">
+		<input type="hidden" name="csrf_token" value="{{.}}">
+		<input type="text" name="username" placeholder="Username">
+		<input type="password" name="password" placeholder="Password">
+		<button type="submit">Login</button>
+	</form>
+</body>
+</html>`))
+
+var uploadFormTemplate = template.Must(template.New("upload").Parse(`<html>
+<head><title>Upload</title></head>
+<body>
+	<form method="POST" action="/upload" enctype="multipart/form-data">
+		<input type="hidden" name="csrf_token" value="{{.}}">
+		<input type="file" name="file">
+		<button type="submit">Upload</button>
+	</form>
+</body>
+</html>`))
+
+func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	s.renderForm(w, r, loginFormTemplate)
+}
+
+func (s *Server) handleUploadForm(w http.ResponseWriter, r *http.Request) {
+	s.renderForm(w, r, uploadFormTemplate)
+}
+
+func (s *Server) renderForm(w http.ResponseWriter, r *http.Request, tmpl *template.Template) {
+	token := s.issueCSRFToken(s.csrfSession(w, r))
+	
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := tmpl.Execute(w, token); err != nil {
+		log.Printf("Failed to render %s form: %v", tmpl.Name(), err)
+	}
+}
+
+func (s *Server) csrfSessionID(r *http.Request) string {
+	if id, _, exists := s.session(r); exists {
+		return id
+	}
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return csrfCookieName + ":" + cookie.Value
+	}
+	return ""
+}
+
+func (s *Server) csrfSession(w http.ResponseWriter, r *http.Request) string {
+	if id := s.csrfSessionID(r); id != "" {
+		return id
+	}
+	
+	value := generateToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return csrfCookieName + ":" + value
+}
+
+// issueCSRFToken derives the token from the session ID with an HMAC, so no
+// per-client state is kept on the server.
+func (s *Server) issueCSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, s.csrfKey)
+	mac.Write([]byte(sessionID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) validCSRF(r *http.Request, submitted string) bool {
+	sessionID := s.csrfSessionID(r)
+	if sessionID == "" || submitted == "" {
+		return false
+	}
+	
+	return hmac.Equal([]byte(submitted), []byte(s.issueCSRFToken(sessionID)))
+}
+
 func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(32 << 20)
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	
+	reader, err := r.MultipartReader()
 	if err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 	
-	file, header, err := r.FormFile("file")
-	if err != nil {
+	// The form puts the token before the file, so it is checked before any
+	// of the upload is read.
+	submitted := r.Header.Get(csrfHeaderName)
+	file, err := reader.NextPart()
+	if err == nil && file.FormName() == csrfFieldName {
+		value, _ := io.ReadAll(io.LimitReader(file, 256))
+		if submitted == "" {
+			submitted = string(value)
+		}
+		file, err = reader.NextPart()
+	}
+	
+	if !s.validCSRF(r, submitted) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	
+	if err != nil || file.FormName() != "file" {
 		http.Error(w, "No file uploaded", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 	
-	filename := header.Filename
+	filename := file.FileName()
 	if filename == "" {
 		filename = fmt.Sprintf("upload_%d", time.Now().Unix())
 	}
@@ -251,22 +548,48 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	submitted := r.FormValue(csrfFieldName)
+	if submitted == "" {
+		submitted = r.Header.Get(csrfHeaderName)
+	}
+	if !s.validCSRF(r, submitted) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 	
-	user, exists := users[username]
-	if !exists || user.Password != password {
+	keys := []string{"user:" + username, "ip:" + clientIP(r)}
+	if err := s.logins.Allow(keys...); err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	
+	user, err := s.users.GetByUsername(username)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+		return
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		user = User{PasswordHash: dummyPasswordHash}
+	}
+	if !user.CheckPassword(password) || err != nil {
+		s.logins.Fail(keys...)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	s.logins.Reset(keys...)
 	
 	token := generateToken()
+	s.sessionsMu.Lock()
 	s.sessions[token] = Session{
 		UserID:   user.ID,
 		Username: user.Username,
 		IsAdmin:  user.IsAdmin,
 		Created:  time.Now(),
 	}
+	s.sessionsMu.Unlock()
 	
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session",
@@ -282,13 +605,12 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err != nil {
+	if _, err := r.Cookie("session"); err != nil {
 		http.Error(w, "No session found", http.StatusUnauthorized)
 		return
 	}
 	
-	session, exists := s.sessions[cookie.Value]
+	sessionID, session, exists := s.session(r)
 	if !exists {
 		http.Error(w, "Invalid session", http.StatusUnauthorized)
 		return
@@ -299,7 +621,7 @@ func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
 		"username":  session.Username,
 		"is_admin":  session.IsAdmin,
 		"created":   session.Created,
-		"session_id": cookie.Value,
+		"session_id": sessionID,
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -307,13 +629,12 @@ func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAdminPanel(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err != nil {
+	if _, err := r.Cookie("session"); err != nil {
 		http.Error(w, "No session found", http.StatusUnauthorized)
 		return
 	}
 	
-	session, exists := s.sessions[cookie.Value]
+	_, session, exists := s.session(r)
 	if !exists || !session.IsAdmin {
 		http.Error(w, "Access denied", http.StatusForbidden)
 		return
@@ -334,15 +655,10 @@ func (s *Server) handleAdminPanel(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
-	var userList []map[string]interface{}
-	for _, user := range users {
-		userList = append(userList, map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-			"password": user.Password,
-			"is_admin": user.IsAdmin,
-		})
+	userList, err := s.users.List()
+	if err != nil {
+		http.Error(w, "Failed to list users", http.StatusInternalServerError)
+		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -356,7 +672,14 @@ func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	delete(users, userID)
+	if err := s.users.Delete(userID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
 	
 	response := fmt.Sprintf("User %s deleted successfully", userID)
 	w.Header().Set("Content-Type", "text/html")
@@ -380,6 +703,14 @@ func (s *Server) getSystemInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(info)
 }
 
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func generateToken() string {
 	b := make([]byte, 16)
 	rand.Read(b)
@@ -394,6 +725,13 @@ func main() {
 		}
 	}
 	
-	server := NewServer(port)
-	log.Fatal(server.Start())
+	users, err := newDemoUserStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	
+	server := NewServer(port, users)
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
 } 
\ No newline at end of file
@@ -1,19 +1,117 @@
 package main
 
 import (
+	"container/list"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+	"go-security-scan/loginguard"
 )
 
 type Database struct {
-	db *sql.DB
+	db            *sql.DB
+	cacheCapacity int
+	userCache     *lruCache
+	productCache  *lruCache
+	cacheMu       sync.Mutex
+	migrations    []Migration
+	opMu          sync.Mutex
+	closing       bool
+	inflight      sync.WaitGroup
+	authStmt      *sql.Stmt
+	logins        *loginguard.Limiter
+}
+
+var ErrDatabaseClosed = errors.New("database is closed")
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+const defaultCacheCapacity = 256
+
+const (
+	maxLoginAttempts = 5
+	loginWindow      = 15 * time.Minute
+	loginLockout     = time.Minute
+	maxLoginLockout  = time.Hour
+)
+
+const defaultPageSize = 50
+
+type lruEntry struct {
+	key   int
+	value interface{}
+}
+
+type lruCache struct {
+	capacity int
+	items    map[int]*list.Element
+	order    *list.List
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    make(map[int]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) Get(key int) (interface{}, bool) {
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+func (c *lruCache) Put(key int, value interface{}) {
+	if c.capacity <= 0 {
+		return
+	}
+	
+	if elem, exists := c.items[key]; exists {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	c.evict()
+}
+
+func (c *lruCache) Remove(key int) {
+	if elem, exists := c.items[key]; exists {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+func (c *lruCache) Resize(capacity int) {
+	c.capacity = capacity
+	c.evict()
+}
+
+func (c *lruCache) evict() {
+	for c.order.Len() > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
 }
 
 type User struct {
@@ -45,80 +143,266 @@ type Order struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type OrderDetail struct {
+	OrderID     int       `json:"order_id"`
+	UserID      int       `json:"user_id"`
+	ProductID   int       `json:"product_id"`
+	Quantity    int       `json:"quantity"`
+	Total       float64   `json:"total"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	ProductName string    `json:"product_name"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+}
+
 func NewDatabase(dbPath string) (*Database, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, err
 	}
 
-	database := &Database{db: db}
-	err = database.createTables()
+	database := &Database{
+		db:            db,
+		cacheCapacity: defaultCacheCapacity,
+		userCache:     newLRUCache(defaultCacheCapacity),
+		productCache:  newLRUCache(defaultCacheCapacity),
+		migrations:    getMigrations(),
+		logins:        loginguard.New(maxLoginAttempts, loginWindow, loginLockout, maxLoginLockout),
+	}
+	err = database.RunMigrations()
 	if err != nil {
 		return nil, err
 	}
 
+	database.authStmt, err = db.Prepare("SELECT id, username, password, email, is_admin, created_at, last_login FROM users WHERE username = ? AND password = ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statements: %w", err)
+	}
+
 	return database, nil
 }
 
-func (d *Database) createTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT UNIQUE NOT NULL,
-			password TEXT NOT NULL,
-			email TEXT UNIQUE NOT NULL,
-			is_admin INTEGER DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			last_login DATETIME
-		)`,
-		`CREATE TABLE IF NOT EXISTS products (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
+func (d *Database) SetCacheCapacity(capacity int) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	
+	d.cacheCapacity = capacity
+	d.userCache.Resize(capacity)
+	d.productCache.Resize(capacity)
+}
+
+func (d *Database) CacheCapacity() int {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	return d.cacheCapacity
+}
+
+func (d *Database) cachedUser(userID int) (*User, bool) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	
+	value, exists := d.userCache.Get(userID)
+	if !exists {
+		return nil, false
+	}
+	user := value.(User)
+	return &user, true
+}
+
+func (d *Database) cacheUser(user User) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	d.userCache.Put(user.ID, user)
+}
+
+func (d *Database) invalidateUser(userID int) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	d.userCache.Remove(userID)
+}
+
+func (d *Database) cachedProduct(productID int) (*Product, bool) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	
+	value, exists := d.productCache.Get(productID)
+	if !exists {
+		return nil, false
+	}
+	product := value.(Product)
+	return &product, true
+}
+
+func (d *Database) cacheProduct(product Product) {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	d.productCache.Put(product.ID, product)
+}
+
+func (d *Database) clearCaches() {
+	d.cacheMu.Lock()
+	defer d.cacheMu.Unlock()
+	d.userCache = newLRUCache(d.cacheCapacity)
+	d.productCache = newLRUCache(d.cacheCapacity)
+}
+
+func getMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_initial_tables",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS users (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					username TEXT UNIQUE NOT NULL,
+					password TEXT NOT NULL,
+					email TEXT UNIQUE NOT NULL,
+					is_admin INTEGER DEFAULT 0,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					last_login DATETIME
+				);
+				CREATE TABLE IF NOT EXISTS products (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL,
+					description TEXT,
+					price REAL NOT NULL,
+					category TEXT,
+					stock INTEGER DEFAULT 0
+				);
+				CREATE TABLE IF NOT EXISTS orders (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL,
+					product_id INTEGER NOT NULL,
+					quantity INTEGER NOT NULL,
+					total REAL NOT NULL,
+					status TEXT DEFAULT 'pending',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (user_id) REFERENCES users (id),
+					FOREIGN KEY (product_id) REFERENCES products (id)
+				);
+			`,
+		},
+	}
+}
+
+func (d *Database) RunMigrations() error {
+	_, err := d.db.Exec(`
+		CREATE TABLE IF NOT EXISTS migration_history (
+			version INTEGER PRIMARY KEY,
 			name TEXT NOT NULL,
-			description TEXT,
-			price REAL NOT NULL,
-			category TEXT,
-			stock INTEGER DEFAULT 0
-		)`,
-		`CREATE TABLE IF NOT EXISTS orders (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER NOT NULL,
-			product_id INTEGER NOT NULL,
-			quantity INTEGER NOT NULL,
-			total REAL NOT NULL,
-			status TEXT DEFAULT 'pending',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users (id),
-			FOREIGN KEY (product_id) REFERENCES products (id)
-		)`,
-	}
-
-	for _, query := range queries {
-		_, err := d.db.Exec(query)
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migration history table: %w", err)
+	}
+	
+	appliedMigrations := make(map[int]bool)
+	rows, err := d.db.Query("SELECT version FROM migration_history")
+	if err != nil {
+		return fmt.Errorf("failed to query migration history: %w", err)
+	}
+	
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		appliedMigrations[version] = true
+	}
+	rows.Close()
+	
+	for _, migration := range d.migrations {
+		if appliedMigrations[migration.Version] {
+			continue
+		}
+		
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+		
+		tx, err := d.db.Begin()
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to begin transaction for migration %d: %w", migration.Version, err)
+		}
+		
+		if _, err := tx.Exec(migration.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to execute migration %d: %w", migration.Version, err)
+		}
+		
+		if _, err := tx.Exec("INSERT INTO migration_history (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
 		}
 	}
-
+	
 	return nil
 }
 
+var (
+	ErrDuplicateEmail    = errors.New("email already registered")
+	ErrDuplicateUsername = errors.New("username already taken")
+)
+
 func (d *Database) AddUser(user User) error {
-	query := fmt.Sprintf("INSERT INTO users (username, password, email, is_admin) VALUES ('%s', '%s', '%s', %d)",
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
+	_, err := d.db.Exec("INSERT INTO users (username, password, email, is_admin) VALUES (?, ?, ?, ?)",
 		user.Username, user.Password, user.Email, boolToInt(user.IsAdmin))
+	if err != nil {
+		return uniqueViolation(err)
+	}
+	return nil
+}
+
+func uniqueViolation(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
 	
-	_, err := d.db.Exec(query)
+	switch {
+	case strings.Contains(sqliteErr.Error(), "users.email"):
+		return ErrDuplicateEmail
+	case strings.Contains(sqliteErr.Error(), "users.username"):
+		return ErrDuplicateUsername
+	}
 	return err
 }
 
 func (d *Database) AuthenticateUser(username, password string) (*User, error) {
-	query := fmt.Sprintf("SELECT id, username, password, email, is_admin, created_at, last_login FROM users WHERE username='%s' AND password='%s'",
-		username, password)
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
 	
-	row := d.db.QueryRow(query)
+	key := "user:" + username
+	if err := d.logins.Allow(key); err != nil {
+		return nil, err
+	}
+	
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 	
 	var user User
 	var lastLogin sql.NullTime
-	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.IsAdmin, &user.CreatedAt, &lastLogin)
+	err = tx.Stmt(d.authStmt).QueryRow(username, password).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.IsAdmin, &user.CreatedAt, &lastLogin)
+	if err == sql.ErrNoRows {
+		d.logins.Fail(key)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -127,25 +411,52 @@ func (d *Database) AuthenticateUser(username, password string) (*User, error) {
 		user.LastLogin = lastLogin.Time
 	}
 	
-	updateQuery := fmt.Sprintf("UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = %d", user.ID)
-	d.db.Exec(updateQuery)
+	if _, err := tx.Exec("UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = ?", user.ID); err != nil {
+		return nil, fmt.Errorf("failed to update last login: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	d.logins.Reset(key)
+	d.invalidateUser(user.ID)
 	
 	return &user, nil
 }
 
 func (d *Database) UpdateUserPassword(userID int, newPassword string) error {
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
 	query := fmt.Sprintf("UPDATE users SET password='%s' WHERE id=%d", newPassword, userID)
 	_, err := d.db.Exec(query)
+	d.invalidateUser(userID)
 	return err
 }
 
 func (d *Database) DeleteUser(userID int) error {
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
 	query := fmt.Sprintf("DELETE FROM users WHERE id=%d", userID)
 	_, err := d.db.Exec(query)
+	d.invalidateUser(userID)
 	return err
 }
 
 func (d *Database) GetUserByID(userID int) (*User, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
+	
+	if user, exists := d.cachedUser(userID); exists {
+		return user, nil
+	}
+	
 	query := fmt.Sprintf("SELECT id, username, password, email, is_admin, created_at, last_login FROM users WHERE id=%d", userID)
 	
 	row := d.db.QueryRow(query)
@@ -161,14 +472,31 @@ func (d *Database) GetUserByID(userID int) (*User, error) {
 		user.LastLogin = lastLogin.Time
 	}
 	
+	d.cacheUser(user)
 	return &user, nil
 }
 
-func (d *Database) SearchUsers(searchTerm string) ([]User, error) {
-	query := fmt.Sprintf("SELECT id, username, password, email, is_admin, created_at, last_login FROM users WHERE username LIKE '%%%s%%' OR email LIKE '%%%s%%'",
-		searchTerm, searchTerm)
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (d *Database) SearchUsers(searchTerm string, limit, offset int) ([]User, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
 	
-	rows, err := d.db.Query(query)
+	limit, offset = normalizePage(limit, offset)
+	pattern := "%" + searchTerm + "%"
+	query := "SELECT id, username, password, email, is_admin, created_at, last_login FROM users WHERE username LIKE ? OR email LIKE ? ORDER BY id LIMIT ? OFFSET ?"
+	
+	rows, err := d.db.Query(query, pattern, pattern, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +521,25 @@ func (d *Database) SearchUsers(searchTerm string) ([]User, error) {
 	return users, nil
 }
 
+func (d *Database) CountSearchUsers(searchTerm string) (int, error) {
+	if err := d.track(); err != nil {
+		return 0, err
+	}
+	defer d.inflight.Done()
+	
+	pattern := "%" + searchTerm + "%"
+	
+	var total int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM users WHERE username LIKE ? OR email LIKE ?", pattern, pattern).Scan(&total)
+	return total, err
+}
+
 func (d *Database) AddProduct(product Product) error {
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
 	query := fmt.Sprintf("INSERT INTO products (name, description, price, category, stock) VALUES ('%s', '%s', %f, '%s', %d)",
 		product.Name, product.Description, product.Price, product.Category, product.Stock)
 	
@@ -202,6 +548,15 @@ func (d *Database) AddProduct(product Product) error {
 }
 
 func (d *Database) GetProductByID(productID int) (*Product, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
+	
+	if product, exists := d.cachedProduct(productID); exists {
+		return product, nil
+	}
+	
 	query := fmt.Sprintf("SELECT id, name, description, price, category, stock FROM products WHERE id=%d", productID)
 	
 	row := d.db.QueryRow(query)
@@ -212,14 +567,21 @@ func (d *Database) GetProductByID(productID int) (*Product, error) {
 		return nil, err
 	}
 	
+	d.cacheProduct(product)
 	return &product, nil
 }
 
-func (d *Database) SearchProducts(searchTerm string) ([]Product, error) {
-	query := fmt.Sprintf("SELECT id, name, description, price, category, stock FROM products WHERE name LIKE '%%%s%%' OR description LIKE '%%%s%%' OR category LIKE '%%%s%%'",
-		searchTerm, searchTerm, searchTerm)
+func (d *Database) SearchProducts(searchTerm string, limit, offset int) ([]Product, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
 	
-	rows, err := d.db.Query(query)
+	limit, offset = normalizePage(limit, offset)
+	pattern := "%" + searchTerm + "%"
+	query := "SELECT id, name, description, price, category, stock FROM products WHERE name LIKE ? OR description LIKE ? OR category LIKE ? ORDER BY id LIMIT ? OFFSET ?"
+	
+	rows, err := d.db.Query(query, pattern, pattern, pattern, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +601,25 @@ func (d *Database) SearchProducts(searchTerm string) ([]Product, error) {
 	return products, nil
 }
 
+func (d *Database) CountSearchProducts(searchTerm string) (int, error) {
+	if err := d.track(); err != nil {
+		return 0, err
+	}
+	defer d.inflight.Done()
+	
+	pattern := "%" + searchTerm + "%"
+	
+	var total int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM products WHERE name LIKE ? OR description LIKE ? OR category LIKE ?", pattern, pattern, pattern).Scan(&total)
+	return total, err
+}
+
 func (d *Database) CreateOrder(order Order) error {
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
 	query := fmt.Sprintf("INSERT INTO orders (user_id, product_id, quantity, total, status) VALUES (%d, %d, %d, %f, '%s')",
 		order.UserID, order.ProductID, order.Quantity, order.Total, order.Status)
 	
@@ -247,10 +627,47 @@ func (d *Database) CreateOrder(order Order) error {
 	return err
 }
 
-func (d *Database) GetOrdersByUserID(userID int) ([]Order, error) {
-	query := fmt.Sprintf("SELECT id, user_id, product_id, quantity, total, status, created_at FROM orders WHERE user_id=%d", userID)
+type OrderFilter struct {
+	Status string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+func (f OrderFilter) where(userID int) (string, []interface{}) {
+	clause := "WHERE user_id = ?"
+	args := []interface{}{userID}
 	
-	rows, err := d.db.Query(query)
+	if f.Status != "" {
+		clause += " AND status = ?"
+		args = append(args, f.Status)
+	}
+	if !f.Since.IsZero() {
+		clause += " AND created_at >= ?"
+		args = append(args, f.Since.UTC().Format(sqliteTimeFormat))
+	}
+	if !f.Until.IsZero() {
+		clause += " AND created_at < ?"
+		args = append(args, f.Until.UTC().Format(sqliteTimeFormat))
+	}
+	
+	return clause, args
+}
+
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+func (d *Database) GetOrdersByUserID(userID int, filter OrderFilter) ([]Order, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
+	
+	limit, offset := normalizePage(filter.Limit, filter.Offset)
+	where, args := filter.where(userID)
+	query := "SELECT id, user_id, product_id, quantity, total, status, created_at FROM orders " + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	
+	rows, err := d.db.Query(query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
@@ -270,56 +687,87 @@ func (d *Database) GetOrdersByUserID(userID int) ([]Order, error) {
 	return orders, nil
 }
 
+func (d *Database) CountOrdersByUserID(userID int, filter OrderFilter) (int, error) {
+	if err := d.track(); err != nil {
+		return 0, err
+	}
+	defer d.inflight.Done()
+	
+	where, args := filter.where(userID)
+	
+	var total int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM orders "+where, args...).Scan(&total)
+	return total, err
+}
+
 func (d *Database) UpdateOrderStatus(orderID int, status string) error {
+	if err := d.track(); err != nil {
+		return err
+	}
+	defer d.inflight.Done()
+	
 	query := fmt.Sprintf("UPDATE orders SET status='%s' WHERE id=%d", status, orderID)
 	_, err := d.db.Exec(query)
 	return err
 }
 
-func (d *Database) GetUserOrdersWithDetails(userID int) ([]map[string]interface{}, error) {
-	query := fmt.Sprintf(`
+func (d *Database) GetUserOrderDetails(userID int) ([]OrderDetail, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
+	
+	rows, err := d.db.Query(`
 		SELECT o.id, o.user_id, o.product_id, o.quantity, o.total, o.status, o.created_at,
 		       u.username, u.email,
-		       p.name, p.description, p.price
+		       p.name, COALESCE(p.description, ''), p.price
 		FROM orders o
 		JOIN users u ON o.user_id = u.id
 		JOIN products p ON o.product_id = p.id
-		WHERE o.user_id = %d
+		WHERE o.user_id = ?
 	`, userID)
-	
-	rows, err := d.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	
-	var results []map[string]interface{}
+	var details []OrderDetail
 	for rows.Next() {
-		var orderID, userID, productID, quantity int
-		var total float64
-		var status, username, email, productName, description string
-		var price float64
-		var createdAt time.Time
-		
-		err := rows.Scan(&orderID, &userID, &productID, &quantity, &total, &status, &createdAt,
-			&username, &email, &productName, &description, &price)
+		var detail OrderDetail
+		err := rows.Scan(&detail.OrderID, &detail.UserID, &detail.ProductID, &detail.Quantity, &detail.Total, &detail.Status, &detail.CreatedAt,
+			&detail.Username, &detail.Email, &detail.ProductName, &detail.Description, &detail.Price)
 		if err != nil {
 			return nil, err
 		}
 		
+		details = append(details, detail)
+	}
+	
+	return details, rows.Err()
+}
+
+// Deprecated: use GetUserOrderDetails, which returns typed results.
+func (d *Database) GetUserOrdersWithDetails(userID int) ([]map[string]interface{}, error) {
+	details, err := d.GetUserOrderDetails(userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	var results []map[string]interface{}
+	for _, detail := range details {
 		result := map[string]interface{}{
-			"order_id":      orderID,
-			"user_id":       userID,
-			"product_id":    productID,
-			"quantity":      quantity,
-			"total":         total,
-			"status":        status,
-			"created_at":    createdAt,
-			"username":      username,
-			"email":         email,
-			"product_name":  productName,
-			"description":   description,
-			"price":         price,
+			"order_id":      detail.OrderID,
+			"user_id":       detail.UserID,
+			"product_id":    detail.ProductID,
+			"quantity":      detail.Quantity,
+			"total":         detail.Total,
+			"status":        detail.Status,
+			"created_at":    detail.CreatedAt,
+			"username":      detail.Username,
+			"email":         detail.Email,
+			"product_name":  detail.ProductName,
+			"description":   detail.Description,
+			"price":         detail.Price,
 		}
 		
 		results = append(results, result)
@@ -329,11 +777,17 @@ func (d *Database) GetUserOrdersWithDetails(userID int) ([]map[string]interface{
 }
 
 func (d *Database) ExecuteCustomQuery(query string) ([]map[string]interface{}, error) {
+	if err := d.track(); err != nil {
+		return nil, err
+	}
+	defer d.inflight.Done()
+	
 	rows, err := d.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	defer d.clearCaches()
 	
 	columns, err := rows.Columns()
 	if err != nil {
@@ -364,7 +818,48 @@ func (d *Database) ExecuteCustomQuery(query string) ([]map[string]interface{}, e
 	return results, nil
 }
 
+func (d *Database) track() error {
+	d.opMu.Lock()
+	defer d.opMu.Unlock()
+	
+	if d.closing {
+		return ErrDatabaseClosed
+	}
+	d.inflight.Add(1)
+	return nil
+}
+
+func (d *Database) CloseWithContext(ctx context.Context) error {
+	d.opMu.Lock()
+	d.closing = true
+	d.opMu.Unlock()
+	
+	drained := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(drained)
+	}()
+	
+	select {
+	case <-drained:
+		return d.closeDB()
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for in-flight operations: %w", ctx.Err())
+	}
+}
+
 func (d *Database) Close() error {
+	d.opMu.Lock()
+	d.closing = true
+	d.opMu.Unlock()
+	
+	return d.closeDB()
+}
+
+func (d *Database) closeDB() error {
+	if d.authStmt != nil {
+		d.authStmt.Close()
+	}
 	return d.db.Close()
 }
 
@@ -384,10 +879,10 @@ func main() {
 		fmt.Println("  update_password <user_id> <new_password>")
 		fmt.Println("  delete_user <user_id>")
 		fmt.Println("  get_user <user_id>")
-		fmt.Println("  search_users <term>")
+		fmt.Println("  search_users <term> [limit] [offset]")
 		fmt.Println("  add_product <name> <description> <price> <category> <stock>")
 		fmt.Println("  get_product <product_id>")
-		fmt.Println("  search_products <term>")
+		fmt.Println("  search_products <term> [limit] [offset]")
 		fmt.Println("  create_order <user_id> <product_id> <quantity> <total>")
 		fmt.Println("  get_orders <user_id>")
 		fmt.Println("  update_order <order_id> <status>")
@@ -510,20 +1005,37 @@ func main() {
 		
 	case "search_users":
 		if len(os.Args) < 3 {
-			fmt.Println("Usage: search_users <term>")
+			fmt.Println("Usage: search_users <term> [limit] [offset]")
 			return
 		}
 		
 		searchTerm := os.Args[2]
+		limit, offset := 0, 0
+		if len(os.Args) > 3 {
+			limit, _ = strconv.Atoi(os.Args[3])
+		}
+		if len(os.Args) > 4 {
+			offset, _ = strconv.Atoi(os.Args[4])
+		}
 		
-		users, err := db.SearchUsers(searchTerm)
+		users, err := db.SearchUsers(searchTerm, limit, offset)
 		if err != nil {
 			fmt.Printf("Error searching users: %v\n", err)
-		} else {
-			usersJSON, _ := json.MarshalIndent(users, "", "  ")
-			fmt.Println(string(usersJSON))
+			return
+		}
+		
+		total, err := db.CountSearchUsers(searchTerm)
+		if err != nil {
+			fmt.Printf("Error counting users: %v\n", err)
+			return
 		}
 		
+		usersJSON, _ := json.MarshalIndent(map[string]interface{}{
+			"users": users,
+			"total": total,
+		}, "", "  ")
+		fmt.Println(string(usersJSON))
+		
 	case "custom_query":
 		if len(os.Args) < 3 {
 			fmt.Println("Usage: custom_query <sql_query>")
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddUserReportsDuplicates(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AddUser(User{Username: "alice", Password: "pw", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		user User
+		want error
+	}{
+		{"duplicate email", User{Username: "alice2", Password: "pw", Email: "alice@example.com"}, ErrDuplicateEmail},
+		{"duplicate username", User{Username: "alice", Password: "pw", Email: "other@example.com"}, ErrDuplicateUsername},
+	} {
+		if err := db.AddUser(tc.user); !errors.Is(err, tc.want) {
+			t.Errorf("%s: AddUser = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	if err := db.AddUser(User{Username: "bob", Password: "pw", Email: "bob@example.com"}); err != nil {
+		t.Fatalf("AddUser(bob): %v", err)
+	}
+}
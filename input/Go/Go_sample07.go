@@ -1,24 +1,87 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
+	"net/http"
 	"os"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	
+	"go-security-scan/ringlog"
+)
+
+const (
+	defaultMaxOperations             = 10000
+	defaultFileMode      os.FileMode = 0644
+	defaultDirMode       os.FileMode = 0755
+)
+
+var (
+	ErrAccessDenied = errors.New("access denied")
+	ErrReadOnly     = errors.New("file manager is read-only")
+)
+
+// AccessPolicy decides whether user may perform a mutating operation on a
+// path relative to the root directory. A non-nil error denies the operation.
+type AccessPolicy func(op, path, user string) error
+
+func AllowAll(op, path, user string) error {
+	return nil
+}
+
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
 )
 
 type FileManager struct {
 	rootDir    string
 	uploadDir  string
 	tempDir    string
+	cacheMu    sync.Mutex
 	fileCache  map[string]FileInfo
-	operations []Operation
+	opMu       sync.Mutex
+	operations *ringlog.Log[Operation]
+	dirSizes   map[string]dirSizeEntry
+	
+	FollowSymlinks bool
+	Durable        bool
+	FileMode       os.FileMode
+	DirMode        os.FileMode
+	Policy         AccessPolicy
+	ReadOnly       bool
+	
+	// User identifies the caller to Policy and in the operation log. It
+	// defaults to the current OS user.
+	User string
+	
+	// LogPath is where operations are appended; empty keeps the log in
+	// memory only. Once the file would grow past MaxLogSize bytes it is
+	// renamed to LogPath+".1" and a new file is started.
+	LogPath    string
+	LogFormat  LogFormat
+	MaxLogSize int64
+}
+
+type dirSizeEntry struct {
+	size      int64
+	fileCount int
 }
 
 type FileInfo struct {
@@ -30,6 +93,8 @@ type FileInfo struct {
 	Permissions  string    `json:"permissions"`
 	MD5Hash      string    `json:"md5_hash"`
 	ContentType  string    `json:"content_type"`
+	IsSymlink    bool      `json:"is_symlink"`
+	Target       string    `json:"target,omitempty"`
 }
 
 type Operation struct {
@@ -46,21 +111,61 @@ type SearchResult struct {
 	Count   int        `json:"count"`
 }
 
+type KeepStrategy string
+
+const (
+	KeepOldest KeepStrategy = "oldest"
+	KeepNewest KeepStrategy = "newest"
+)
+
 func NewFileManager(rootDir string) *FileManager {
 	return &FileManager{
 		rootDir:    rootDir,
 		uploadDir:  filepath.Join(rootDir, "uploads"),
 		tempDir:    filepath.Join(rootDir, "temp"),
 		fileCache:  make(map[string]FileInfo),
-		operations: make([]Operation, 0),
+		operations: ringlog.New[Operation](defaultMaxOperations),
+		dirSizes:   make(map[string]dirSizeEntry),
+		FileMode:   defaultFileMode,
+		DirMode:    defaultDirMode,
+		Policy:     AllowAll,
+		User:       currentUsername(),
+		LogPath:    filepath.Join(rootDir, "file_operations.log"),
+		LogFormat:  LogFormatText,
+	}
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
 	}
+	return "anonymous"
+}
+
+// NewReadOnlyFileManager returns a FileManager that refuses every mutating
+// operation with ErrReadOnly and keeps its operation log in memory only.
+func NewReadOnlyFileManager(rootDir string) *FileManager {
+	fm := NewFileManager(rootDir)
+	fm.ReadOnly = true
+	return fm
 }
 
 func (fm *FileManager) Initialize() error {
+	if fm.ReadOnly {
+		info, err := os.Stat(fm.rootDir)
+		if err != nil {
+			return fmt.Errorf("failed to stat root directory: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("root %s is not a directory", fm.rootDir)
+		}
+		return nil
+	}
+	
 	dirs := []string{fm.rootDir, fm.uploadDir, fm.tempDir}
 	
 	for _, dir := range dirs {
-		err := os.MkdirAll(dir, 0755)
+		err := os.MkdirAll(dir, fm.DirMode)
 		if err != nil {
 			return fmt.Errorf("failed to create directory %s: %v", dir, err)
 		}
@@ -77,33 +182,731 @@ func (fm *FileManager) ReadFile(path string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
 	}
 	
-	fm.logOperation("read", path, "anonymous", fmt.Sprintf("Read %d bytes", len(content)))
+	fm.logOperation("read", path, fm.User, fmt.Sprintf("Read %d bytes", len(content)))
+	
+	return content, nil
+}
+
+func (fm *FileManager) WriteFile(path string, content []byte) error {
+	fullPath, err := fm.authorizePath("write", path)
+	if err != nil {
+		return err
+	}
+	
+	parentDir := filepath.Dir(fullPath)
+	err = os.MkdirAll(parentDir, fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	
+	_, statErr := os.Stat(fullPath)
+	created := os.IsNotExist(statErr)
+	
+	err = os.WriteFile(fullPath, content, fm.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %v", path, err)
+	}
+	
+	if err := fm.syncFile(fullPath, created); err != nil {
+		return err
+	}
+	
+	fm.invalidateDirSizes(fullPath)
+	fm.InvalidateCache(path)
+	
+	fm.logOperation("write", path, fm.User, fmt.Sprintf("Wrote %d bytes", len(content)))
+	
+	return nil
+}
+
+func (fm *FileManager) syncFile(fullPath string, created bool) error {
+	if !fm.Durable {
+		return nil
+	}
+	
+	file, err := os.OpenFile(fullPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for sync: %v", fullPath, err)
+	}
+	defer file.Close()
+	
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", fullPath, err)
+	}
+	
+	if created {
+		return syncDir(filepath.Dir(fullPath))
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s for sync: %v", dir, err)
+	}
+	defer d.Close()
+	
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %v", dir, err)
+	}
+	return nil
+}
+
+func (fm *FileManager) AppendFile(path string, content []byte) error {
+	fullPath, err := fm.authorizePath("append", path)
+	if err != nil {
+		return err
+	}
+	
+	err = os.MkdirAll(filepath.Dir(fullPath), fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	
+	file, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fm.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s for append: %v", path, err)
+	}
+	defer file.Close()
+	
+	_, err = file.Write(content)
+	if err != nil {
+		return fmt.Errorf("failed to append to file %s: %v", path, err)
+	}
+	
+	if fm.Durable {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file %s: %v", path, err)
+		}
+	}
+	
+	fm.invalidateDirSizes(fullPath)
+	fm.InvalidateCache(path)
+	
+	fm.logOperation("append", path, fm.User, fmt.Sprintf("Appended %d bytes", len(content)))
+	
+	return nil
+}
+
+func (fm *FileManager) AppendLine(path string, line string) error {
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	return fm.AppendFile(path, []byte(line))
+}
+
+func (fm *FileManager) CopyFile(source, destination string) error {
+	sourcePath, err := fm.resolvePath(source)
+	if err != nil {
+		return err
+	}
+	destPath, err := fm.authorizePath("copy", destination)
+	if err != nil {
+		return err
+	}
+	
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %v", err)
+	}
+	defer sourceFile.Close()
+	
+	parentDir := filepath.Dir(destPath)
+	err = os.MkdirAll(parentDir, fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	
+	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fm.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %v", err)
+	}
+	defer destFile.Close()
+	
+	_, err = io.Copy(destFile, sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %v", err)
+	}
+	
+	if fm.Durable {
+		if err := destFile.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file %s: %v", destination, err)
+		}
+		if err := syncDir(parentDir); err != nil {
+			return err
+		}
+	}
+	
+	fm.invalidateDirSizes(destPath)
+	fm.InvalidateCache(destination)
+	
+	fm.logOperation("copy", fmt.Sprintf("%s -> %s", source, destination), fm.User, "File copied")
+	
+	return nil
+}
+
+func (fm *FileManager) MoveFile(source, destination string) error {
+	sourcePath, err := fm.authorizePath("move", source)
+	if err != nil {
+		return err
+	}
+	destPath, err := fm.authorizePath("move", destination)
+	if err != nil {
+		return err
+	}
+	
+	parentDir := filepath.Dir(destPath)
+	err = os.MkdirAll(parentDir, fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	
+	method := "rename"
+	err = os.Rename(sourcePath, destPath)
+	if errors.Is(err, syscall.EXDEV) {
+		method = "copy fallback"
+		err = fm.moveAcrossDevices(sourcePath, destPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move file: %v", err)
+	}
+	
+	fm.invalidateDirSizes(sourcePath, destPath)
+	fm.InvalidateCache(source, destination)
+	
+	fm.logOperation("move", fmt.Sprintf("%s -> %s", source, destination), fm.User, fmt.Sprintf("File moved (%s)", method))
+	
+	return nil
+}
+
+func (fm *FileManager) moveAcrossDevices(sourcePath, destPath string) error {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot move %s across devices: not a regular file", sourcePath)
+	}
+	
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+	
+	tempFile, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	
+	if _, err := io.Copy(tempFile, sourceFile); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	
+	if fm.Durable {
+		if err := tempFile.Sync(); err != nil {
+			tempFile.Close()
+			os.Remove(tempPath)
+			return err
+		}
+	}
+	
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	
+	if err := os.Chmod(tempPath, info.Mode().Perm()); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	
+	if err := os.Chtimes(tempPath, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	
+	if err := os.Rename(tempPath, destPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	
+	if fm.Durable {
+		if err := syncDir(filepath.Dir(destPath)); err != nil {
+			return err
+		}
+	}
+	
+	return os.Remove(sourcePath)
+}
+
+func (fm *FileManager) DeleteFile(path string) error {
+	fullPath, err := fm.authorizePath("delete", path)
+	if err != nil {
+		return err
+	}
+	
+	err = os.Remove(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to delete file %s: %v", path, err)
+	}
+	
+	fm.invalidateDirSizes(fullPath)
+	fm.InvalidateCache(path)
+	
+	fm.logOperation("delete", path, fm.User, "File deleted")
+	
+	return nil
+}
+
+func (fm *FileManager) CopyDir(source, destination string) error {
+	sourcePath, err := fm.resolvePath(source)
+	if err != nil {
+		return err
+	}
+	destPath, err := fm.authorizePath("copy", destination)
+	if err != nil {
+		return err
+	}
+	
+	if destPath == sourcePath || strings.HasPrefix(destPath, sourcePath+string(filepath.Separator)) {
+		return fmt.Errorf("cannot copy directory %s into itself", source)
+	}
+	
+	var copied []string
+	err = filepath.WalkDir(sourcePath, func(walkPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		
+		rel, err := filepath.Rel(sourcePath, walkPath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destPath, rel)
+		
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case d.Type().IsRegular():
+			if err := fm.copyRegularFile(walkPath, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			copied = append(copied, filepath.Join(destination, rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy directory %s: %v", source, err)
+	}
+	
+	fm.invalidateDirSizes(destPath)
+	fm.InvalidateCache(copied...)
+	
+	fm.logOperation("copy_dir", fmt.Sprintf("%s -> %s", source, destination), fm.User, fmt.Sprintf("Copied %d files", len(copied)))
+	
+	return nil
+}
+
+func (fm *FileManager) copyRegularFile(sourcePath, destPath string, mode os.FileMode) error {
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+	
+	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+	
+	if _, err := io.Copy(destFile, sourceFile); err != nil {
+		return err
+	}
+	
+	if fm.Durable {
+		if err := destFile.Sync(); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(destPath, mode)
+}
+
+func (fm *FileManager) DeleteDir(path string) error {
+	fullPath, err := fm.authorizePath("delete", path)
+	if err != nil {
+		return err
+	}
+	
+	root, err := filepath.Abs(fm.rootDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve root directory: %v", err)
+	}
+	if fullPath == root {
+		return fmt.Errorf("refusing to delete root directory")
+	}
+	
+	var removed, removedFull []string
+	err = filepath.WalkDir(fullPath, func(walkPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		
+		rel, err := filepath.Rel(root, walkPath)
+		if err != nil {
+			return err
+		}
+		if walkPath != fullPath {
+			if err := fm.authorize("delete", rel); err != nil {
+				return err
+			}
+		}
+		removed = append(removed, rel)
+		removedFull = append(removedFull, walkPath)
+		return nil
+	})
+	if errors.Is(err, ErrAccessDenied) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete directory %s: %v", path, err)
+	}
+	
+	if err := os.RemoveAll(fullPath); err != nil {
+		return fmt.Errorf("failed to delete directory %s: %v", path, err)
+	}
+	
+	fm.invalidateDirSizes(removedFull...)
+	fm.InvalidateCache(removed...)
+	
+	fm.logOperation("delete_dir", path, fm.User, fmt.Sprintf("Deleted %d items", len(removed)))
+	
+	return nil
+}
+
+func (fm *FileManager) CreateDirectory(path string) error {
+	fullPath, err := fm.authorizePath("create_dir", path)
+	if err != nil {
+		return err
+	}
+	
+	err = os.MkdirAll(fullPath, fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", path, err)
+	}
+	
+	fm.invalidateDirSizes(fullPath)
+	
+	fm.logOperation("create_dir", path, fm.User, "Directory created")
+	
+	return nil
+}
+
+func (fm *FileManager) ListDirectory(path string) ([]FileInfo, error) {
+	fullPath := filepath.Join(fm.rootDir, path)
+	
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %v", path, err)
+	}
+	
+	var files []FileInfo
+	for _, entry := range entries {
+		filePath := filepath.Join(path, entry.Name())
+		fileInfo, _, err := fm.statFile(filepath.Join(fullPath, entry.Name()), filePath)
+		if err != nil {
+			continue
+		}
+		
+		if !fileInfo.IsDir {
+			fm.populateDetails(filepath.Join(fullPath, entry.Name()), &fileInfo)
+		}
+		
+		files = append(files, fileInfo)
+	}
+	
+	fm.logOperation("list", path, fm.User, fmt.Sprintf("Listed %d items", len(files)))
+	
+	return files, nil
+}
+
+func (fm *FileManager) statFile(fullPath, path string) (FileInfo, os.FileInfo, error) {
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	
+	fileInfo := FileInfo{Name: info.Name(), Path: path}
+	
+	if info.Mode()&os.ModeSymlink != 0 {
+		fileInfo.IsSymlink = true
+		if target, err := os.Readlink(fullPath); err == nil {
+			fileInfo.Target = target
+		}
+		
+		if fm.FollowSymlinks {
+			if targetInfo, err := os.Stat(fullPath); err == nil {
+				info = targetInfo
+			}
+		}
+	}
+	
+	fileInfo.Size = info.Size()
+	fileInfo.ModTime = info.ModTime()
+	fileInfo.IsDir = info.IsDir()
+	fileInfo.Permissions = info.Mode().String()
+	
+	return fileInfo, info, nil
+}
+
+// fileKey identifies a directory independently of the path it was reached
+// through, so symlink loops are detected with a map lookup.
+type fileKey struct {
+	dev  uint64
+	ino  uint64
+	path string
+}
+
+func (fm *FileManager) walkFiles(fullPath, path string, visited map[fileKey]bool, fn func(FileInfo) error) error {
+	fileInfo, info, err := fm.statFile(fullPath, path)
+	if err != nil {
+		return err
+	}
+	
+	if err := fn(fileInfo); err != nil {
+		return err
+	}
+	
+	if !info.IsDir() {
+		return nil
+	}
+	
+	key := fileIdentity(fullPath, info)
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+	
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return err
+	}
+	
+	for _, entry := range entries {
+		err := fm.walkFiles(filepath.Join(fullPath, entry.Name()), filepath.Join(path, entry.Name()), visited, fn)
+		if err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+func (fm *FileManager) SearchFiles(query string, rootPath string) (*SearchResult, error) {
+	var results []FileInfo
+	visited := make(map[fileKey]bool)
+	
+	err := fm.walkFiles(filepath.Join(fm.rootDir, rootPath), filepath.Clean(rootPath), visited, func(fileInfo FileInfo) error {
+		if strings.Contains(strings.ToLower(fileInfo.Name), strings.ToLower(query)) {
+			if !fileInfo.IsDir {
+				hash, err := fm.calculateMD5(fileInfo.Path)
+				if err == nil {
+					fileInfo.MD5Hash = hash
+				}
+			}
+			
+			results = append(results, fileInfo)
+		}
+		
+		return nil
+	})
+	
+	if err != nil {
+		return nil, fmt.Errorf("failed to search files: %v", err)
+	}
+	
+	searchResult := &SearchResult{
+		Query:   query,
+		Results: results,
+		Count:   len(results),
+	}
+	
+	fm.logOperation("search", rootPath, fm.User, fmt.Sprintf("Found %d files matching '%s'", len(results), query))
+	
+	return searchResult, nil
+}
+
+func (fm *FileManager) GetFileInfo(path string) (*FileInfo, error) {
+	fullPath := filepath.Join(fm.rootDir, path)
+	
+	fileInfo, info, err := fm.statFile(fullPath, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info for %s: %v", path, err)
+	}
+	
+	if !info.IsDir() {
+		fm.populateDetails(fullPath, &fileInfo)
+	}
+	
+	fm.logOperation("info", path, fm.User, "File info retrieved")
+	
+	return &fileInfo, nil
+}
+
+func (fm *FileManager) populateDetails(fullPath string, fileInfo *FileInfo) {
+	key := filepath.Clean(fileInfo.Path)
+	
+	fm.cacheMu.Lock()
+	cached, ok := fm.fileCache[key]
+	fm.cacheMu.Unlock()
+	if ok && cached.ModTime.Equal(fileInfo.ModTime) && cached.Size == fileInfo.Size {
+		fileInfo.MD5Hash = cached.MD5Hash
+		fileInfo.ContentType = cached.ContentType
+		return
+	}
+	
+	if hash, err := fm.calculateMD5(fileInfo.Path); err == nil {
+		fileInfo.MD5Hash = hash
+	}
+	fileInfo.ContentType = detectContentType(fullPath)
+	
+	fm.cacheMu.Lock()
+	fm.fileCache[key] = *fileInfo
+	fm.cacheMu.Unlock()
+}
+
+func (fm *FileManager) InvalidateCache(paths ...string) {
+	fm.cacheMu.Lock()
+	defer fm.cacheMu.Unlock()
+	
+	for _, path := range paths {
+		delete(fm.fileCache, filepath.Clean(path))
+	}
+}
+
+func detectContentType(fullPath string) string {
+	byExtension := mime.TypeByExtension(filepath.Ext(fullPath))
+	
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return byExtension
+	}
+	defer file.Close()
+	
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return byExtension
+	}
+	
+	sniffed := http.DetectContentType(header[:n])
+	if sniffed == "application/octet-stream" && byExtension != "" {
+		return byExtension
+	}
+	return sniffed
+}
+
+func (fm *FileManager) UploadFile(filename string, content []byte) error {
+	uploadPath, err := fm.authorizePath("upload", filepath.Join(filepath.Base(fm.uploadDir), filename))
+	if err != nil {
+		return err
+	}
+	
+	_, statErr := os.Stat(uploadPath)
+	created := os.IsNotExist(statErr)
+	
+	err = os.WriteFile(uploadPath, content, fm.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %v", err)
+	}
+	
+	if err := fm.syncFile(uploadPath, created); err != nil {
+		return err
+	}
+	
+	fm.invalidateDirSizes(uploadPath)
+	fm.InvalidateCache(filepath.Join(filepath.Base(fm.uploadDir), filename))
+	
+	fm.logOperation("upload", filename, fm.User, fmt.Sprintf("Uploaded %d bytes", len(content)))
+	
+	return nil
+}
+
+func (fm *FileManager) CompressFile(source, destination string) error {
+	sourcePath, err := fm.resolvePath(source)
+	if err != nil {
+		return err
+	}
+	destPath, err := fm.authorizePath("compress", destination)
+	if err != nil {
+		return err
+	}
+	
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %v", err)
+	}
+	defer sourceFile.Close()
+	
+	err = os.MkdirAll(filepath.Dir(destPath), fm.DirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	
+	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fm.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %v", err)
+	}
+	defer destFile.Close()
 	
-	return content, nil
-}
-
-func (fm *FileManager) WriteFile(path string, content []byte) error {
-	fullPath := filepath.Join(fm.rootDir, path)
+	writer := gzip.NewWriter(destFile)
+	writer.Name = filepath.Base(sourcePath)
 	
-	parentDir := filepath.Dir(fullPath)
-	err := os.MkdirAll(parentDir, 0755)
+	originalSize, err := io.Copy(writer, sourceFile)
 	if err != nil {
-		return fmt.Errorf("failed to create parent directory: %v", err)
+		writer.Close()
+		return fmt.Errorf("failed to compress file: %v", err)
 	}
 	
-	err = os.WriteFile(fullPath, content, 0644)
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %v", err)
+	}
+	
+	compressedInfo, err := destFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to write file %s: %v", path, err)
+		return fmt.Errorf("failed to stat compressed file: %v", err)
 	}
 	
-	fm.logOperation("write", path, "anonymous", fmt.Sprintf("Wrote %d bytes", len(content)))
+	fm.invalidateDirSizes(destPath)
+	fm.InvalidateCache(destination)
+	
+	fm.logOperation("compress", fmt.Sprintf("%s -> %s", source, destination), fm.User,
+		fmt.Sprintf("Compressed %d bytes to %d bytes (ratio %.2f)", originalSize, compressedInfo.Size(), compressionRatio(originalSize, compressedInfo.Size())))
 	
 	return nil
 }
 
-func (fm *FileManager) CopyFile(source, destination string) error {
-	sourcePath := filepath.Join(fm.rootDir, source)
-	destPath := filepath.Join(fm.rootDir, destination)
+func (fm *FileManager) DecompressFile(source, destination string) error {
+	sourcePath, err := fm.resolvePath(source)
+	if err != nil {
+		return err
+	}
+	destPath, err := fm.authorizePath("decompress", destination)
+	if err != nil {
+		return err
+	}
 	
 	sourceFile, err := os.Open(sourcePath)
 	if err != nil {
@@ -111,201 +914,293 @@ func (fm *FileManager) CopyFile(source, destination string) error {
 	}
 	defer sourceFile.Close()
 	
-	parentDir := filepath.Dir(destPath)
-	err = os.MkdirAll(parentDir, 0755)
+	reader, err := gzip.NewReader(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header: %v", err)
+	}
+	defer reader.Close()
+	
+	err = os.MkdirAll(filepath.Dir(destPath), fm.DirMode)
 	if err != nil {
 		return fmt.Errorf("failed to create parent directory: %v", err)
 	}
 	
-	destFile, err := os.Create(destPath)
+	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fm.FileMode)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %v", err)
 	}
 	defer destFile.Close()
 	
-	_, err = io.Copy(destFile, sourceFile)
+	originalSize, err := io.Copy(destFile, reader)
 	if err != nil {
-		return fmt.Errorf("failed to copy file: %v", err)
+		return fmt.Errorf("failed to decompress file: %v", err)
 	}
 	
-	fm.logOperation("copy", fmt.Sprintf("%s -> %s", source, destination), "anonymous", "File copied")
-	
-	return nil
-}
-
-func (fm *FileManager) MoveFile(source, destination string) error {
-	sourcePath := filepath.Join(fm.rootDir, source)
-	destPath := filepath.Join(fm.rootDir, destination)
-	
-	parentDir := filepath.Dir(destPath)
-	err := os.MkdirAll(parentDir, 0755)
+	compressedInfo, err := sourceFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to create parent directory: %v", err)
+		return fmt.Errorf("failed to stat compressed file: %v", err)
 	}
 	
-	err = os.Rename(sourcePath, destPath)
-	if err != nil {
-		return fmt.Errorf("failed to move file: %v", err)
-	}
+	fm.invalidateDirSizes(destPath)
+	fm.InvalidateCache(destination)
 	
-	fm.logOperation("move", fmt.Sprintf("%s -> %s", source, destination), "anonymous", "File moved")
+	fm.logOperation("decompress", fmt.Sprintf("%s -> %s", source, destination), fm.User,
+		fmt.Sprintf("Decompressed %d bytes to %d bytes (ratio %.2f)", compressedInfo.Size(), originalSize, compressionRatio(originalSize, compressedInfo.Size())))
 	
 	return nil
 }
 
-func (fm *FileManager) DeleteFile(path string) error {
-	fullPath := filepath.Join(fm.rootDir, path)
-	
-	err := os.Remove(fullPath)
-	if err != nil {
-		return fmt.Errorf("failed to delete file %s: %v", path, err)
+func compressionRatio(originalSize, compressedSize int64) float64 {
+	if originalSize == 0 {
+		return 0
 	}
-	
-	fm.logOperation("delete", path, "anonymous", "File deleted")
-	
-	return nil
+	return float64(compressedSize) / float64(originalSize)
 }
 
-func (fm *FileManager) CreateDirectory(path string) error {
-	fullPath := filepath.Join(fm.rootDir, path)
-	
-	err := os.MkdirAll(fullPath, 0755)
+func (fm *FileManager) FindDuplicates(rootPath string) (map[string][]FileInfo, error) {
+	searchRoot, err := fm.resolvePath(rootPath)
 	if err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", path, err)
+		return nil, err
 	}
 	
-	fm.logOperation("create_dir", path, "anonymous", "Directory created")
-	
-	return nil
-}
-
-func (fm *FileManager) ListDirectory(path string) ([]FileInfo, error) {
-	fullPath := filepath.Join(fm.rootDir, path)
-	
-	entries, err := os.ReadDir(fullPath)
+	absRoot, err := filepath.Abs(fm.rootDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory %s: %v", path, err)
+		return nil, fmt.Errorf("failed to resolve root directory: %v", err)
 	}
 	
-	var files []FileInfo
-	for _, entry := range entries {
-		info, err := entry.Info()
+	bySize := make(map[int64][]FileInfo)
+	err = filepath.Walk(searchRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			continue
+			return err
 		}
 		
-		filePath := filepath.Join(path, entry.Name())
-		fileInfo := FileInfo{
-			Name:        entry.Name(),
-			Path:        filePath,
+		if !info.Mode().IsRegular() || info.Size() == 0 {
+			return nil
+		}
+		
+		relativePath, err := filepath.Rel(absRoot, path)
+		if err != nil {
+			return err
+		}
+		
+		bySize[info.Size()] = append(bySize[info.Size()], FileInfo{
+			Name:        info.Name(),
+			Path:        relativePath,
 			Size:        info.Size(),
 			ModTime:     info.ModTime(),
-			IsDir:       entry.IsDir(),
+			IsDir:       false,
 			Permissions: info.Mode().String(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for duplicates: %v", err)
+	}
+	
+	byHash := make(map[string][]FileInfo)
+	for _, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
 		}
 		
-		if !entry.IsDir() {
-			hash, err := fm.calculateMD5(filePath)
-			if err == nil {
-				fileInfo.MD5Hash = hash
+		for _, candidate := range candidates {
+			hash, err := fm.calculateMD5(candidate.Path)
+			if err != nil {
+				continue
 			}
+			candidate.MD5Hash = hash
+			byHash[hash] = append(byHash[hash], candidate)
+		}
+	}
+	
+	duplicates := make(map[string][]FileInfo)
+	for hash, group := range byHash {
+		if len(group) > 1 {
+			duplicates[hash] = group
 		}
-		
-		files = append(files, fileInfo)
 	}
 	
-	fm.logOperation("list", path, "anonymous", fmt.Sprintf("Listed %d items", len(files)))
+	fm.logOperation("find_duplicates", rootPath, fm.User, fmt.Sprintf("Found %d duplicate groups", len(duplicates)))
 	
-	return files, nil
+	return duplicates, nil
 }
 
-func (fm *FileManager) SearchFiles(query string, rootPath string) (*SearchResult, error) {
-	var results []FileInfo
+func (fm *FileManager) DeleteDuplicates(rootPath string, strategy KeepStrategy) ([]string, error) {
+	if strategy != KeepOldest && strategy != KeepNewest {
+		return nil, fmt.Errorf("unknown keep strategy: %s", strategy)
+	}
+	if fm.ReadOnly {
+		return nil, ErrReadOnly
+	}
 	
-	err := filepath.Walk(filepath.Join(fm.rootDir, rootPath), func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	duplicates, err := fm.FindDuplicates(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	
+	var deleted []string
+	for hash, group := range duplicates {
+		sort.Slice(group, func(i, j int) bool {
+			if strategy == KeepNewest {
+				return group[i].ModTime.After(group[j].ModTime)
+			}
+			return group[i].ModTime.Before(group[j].ModTime)
+		})
+		
+		keep := group[0]
+		keptHash, err := fm.calculateMD5(keep.Path)
+		if err != nil || keptHash != hash {
+			continue
 		}
 		
-		if strings.Contains(strings.ToLower(info.Name()), strings.ToLower(query)) {
-			relativePath, _ := filepath.Rel(fm.rootDir, path)
-			fileInfo := FileInfo{
-				Name:        info.Name(),
-				Path:        relativePath,
-				Size:        info.Size(),
-				ModTime:     info.ModTime(),
-				IsDir:       info.IsDir(),
-				Permissions: info.Mode().String(),
+		for _, duplicate := range group[1:] {
+			same, err := fm.sameContents(keep.Path, duplicate.Path)
+			if err != nil || !same {
+				continue
 			}
 			
-			if !info.IsDir() {
-				hash, err := fm.calculateMD5(relativePath)
-				if err == nil {
-					fileInfo.MD5Hash = hash
-				}
+			if err := fm.authorize("delete_duplicate", duplicate.Path); err != nil {
+				continue
 			}
 			
-			results = append(results, fileInfo)
+			if err := os.Remove(filepath.Join(fm.rootDir, duplicate.Path)); err != nil {
+				return deleted, fmt.Errorf("failed to delete duplicate %s: %v", duplicate.Path, err)
+			}
+			
+			deleted = append(deleted, duplicate.Path)
+			fm.invalidateDirSizes(filepath.Join(fm.rootDir, duplicate.Path))
+			fm.InvalidateCache(duplicate.Path)
+			fm.logOperation("delete_duplicate", duplicate.Path, fm.User, fmt.Sprintf("Duplicate of %s removed (kept %s)", keep.Path, strategy))
 		}
-		
-		return nil
-	})
+	}
 	
+	return deleted, nil
+}
+
+func (fm *FileManager) DirSize(path string) (size int64, fileCount int, err error) {
+	fullPath, err := fm.resolvePath(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search files: %v", err)
+		return 0, 0, err
 	}
 	
-	searchResult := &SearchResult{
-		Query:   query,
-		Results: results,
-		Count:   len(results),
+	fm.cacheMu.Lock()
+	cached, exists := fm.dirSizes[fullPath]
+	fm.cacheMu.Unlock()
+	if exists {
+		return cached.size, cached.fileCount, nil
+	}
+	
+	err = filepath.WalkDir(fullPath, func(walkPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		
+		size += info.Size()
+		fileCount++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to calculate size of %s: %v", path, err)
 	}
 	
-	fm.logOperation("search", rootPath, "anonymous", fmt.Sprintf("Found %d files matching '%s'", len(results), query))
+	fm.cacheMu.Lock()
+	fm.dirSizes[fullPath] = dirSizeEntry{size: size, fileCount: fileCount}
+	fm.cacheMu.Unlock()
+	fm.logOperation("dir_size", path, fm.User, fmt.Sprintf("%d bytes in %d files", size, fileCount))
 	
-	return searchResult, nil
+	return size, fileCount, nil
 }
 
-func (fm *FileManager) GetFileInfo(path string) (*FileInfo, error) {
-	fullPath := filepath.Join(fm.rootDir, path)
+func (fm *FileManager) invalidateDirSizes(fullPaths ...string) {
+	fm.cacheMu.Lock()
+	defer fm.cacheMu.Unlock()
 	
-	info, err := os.Stat(fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info for %s: %v", path, err)
+	if len(fm.dirSizes) == 0 {
+		return
 	}
 	
-	fileInfo := &FileInfo{
-		Name:        info.Name(),
-		Path:        path,
-		Size:        info.Size(),
-		ModTime:     info.ModTime(),
-		IsDir:       info.IsDir(),
-		Permissions: info.Mode().String(),
+	for _, fullPath := range fullPaths {
+		absPath, err := filepath.Abs(fullPath)
+		if err != nil {
+			fm.dirSizes = make(map[string]dirSizeEntry)
+			return
+		}
+		
+		for dir := range fm.dirSizes {
+			if absPath == dir || strings.HasPrefix(absPath, dir+string(filepath.Separator)) {
+				delete(fm.dirSizes, dir)
+			}
+		}
+	}
+}
+
+func (fm *FileManager) authorize(op, path string) error {
+	if fm.ReadOnly {
+		return fmt.Errorf("%w: %s %s", ErrReadOnly, op, path)
 	}
 	
-	if !info.IsDir() {
-		hash, err := fm.calculateMD5(path)
-		if err == nil {
-			fileInfo.MD5Hash = hash
-		}
+	policy := fm.Policy
+	if policy == nil {
+		policy = AllowAll
 	}
 	
-	fm.logOperation("info", path, "anonymous", "File info retrieved")
+	path = filepath.Clean(path)
+	err := policy(op, path, fm.User)
+	if err == nil {
+		return nil
+	}
 	
-	return fileInfo, nil
+	fm.logOperation("denied", path, fm.User, fmt.Sprintf("%s refused: %v", op, err))
+	if errors.Is(err, ErrAccessDenied) {
+		return err
+	}
+	return fmt.Errorf("%w: %s %s: %v", ErrAccessDenied, op, path, err)
 }
 
-func (fm *FileManager) UploadFile(filename string, content []byte) error {
-	uploadPath := filepath.Join(fm.uploadDir, filename)
+// authorizePath confines path to the root directory and checks the policy
+// against the root-relative result, returning the absolute path to operate on.
+func (fm *FileManager) authorizePath(op, path string) (string, error) {
+	fullPath, err := fm.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
 	
-	err := os.WriteFile(uploadPath, content, 0644)
+	root, err := filepath.Abs(fm.rootDir)
 	if err != nil {
-		return fmt.Errorf("failed to upload file: %v", err)
+		return "", fmt.Errorf("failed to resolve root directory: %v", err)
+	}
+	relative, err := filepath.Rel(root, fullPath)
+	if err != nil {
+		return "", err
+	}
+	
+	if err := fm.authorize(op, relative); err != nil {
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func (fm *FileManager) resolvePath(path string) (string, error) {
+	root, err := filepath.Abs(fm.rootDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root directory: %v", err)
 	}
 	
-	fm.logOperation("upload", filename, "anonymous", fmt.Sprintf("Uploaded %d bytes", len(content)))
+	fullPath := filepath.Join(root, path)
+	if fullPath != root && !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s escapes root directory", path)
+	}
 	
-	return nil
+	return fullPath, nil
 }
 
 func (fm *FileManager) calculateMD5(path string) (string, error) {
@@ -326,6 +1221,44 @@ func (fm *FileManager) calculateMD5(path string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
+// sameContents compares two files byte for byte, so a hash collision can never
+// cause a file to be deleted as a duplicate.
+func (fm *FileManager) sameContents(pathA, pathB string) (bool, error) {
+	fileA, err := os.Open(filepath.Join(fm.rootDir, pathA))
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	
+	fileB, err := os.Open(filepath.Join(fm.rootDir, pathB))
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+	
+	bufA := make([]byte, 32*1024)
+	bufB := make([]byte, 32*1024)
+	for {
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
+
 func (fm *FileManager) logOperation(opType, path, user, details string) {
 	operation := Operation{
 		Type:      opType,
@@ -335,22 +1268,40 @@ func (fm *FileManager) logOperation(opType, path, user, details string) {
 		Details:   details,
 	}
 	
-	fm.operations = append(fm.operations, operation)
+	fm.opMu.Lock()
+	defer fm.opMu.Unlock()
 	
-	fm.writeLogEntry(operation)
+	fm.operations.Append(operation)
+	
+	if !fm.ReadOnly && fm.LogPath != "" {
+		fm.writeLogEntry(operation)
+	}
 }
 
 func (fm *FileManager) writeLogEntry(operation Operation) {
-	logFile := filepath.Join(fm.rootDir, "file_operations.log")
+	var entry string
+	if fm.LogFormat == LogFormatJSON {
+		data, err := json.Marshal(operation)
+		if err != nil {
+			return
+		}
+		entry = string(data) + "\n"
+	} else {
+		entry = fmt.Sprintf("[%s] %s: %s by %s - %s\n",
+			operation.Timestamp.Format("2006-01-02 15:04:05"),
+			operation.Type,
+			operation.Path,
+			operation.User,
+			operation.Details)
+	}
 	
-	entry := fmt.Sprintf("[%s] %s: %s by %s - %s\n",
-		operation.Timestamp.Format("2006-01-02 15:04:05"),
-		operation.Type,
-		operation.Path,
-		operation.User,
-		operation.Details)
+	if fm.MaxLogSize > 0 {
+		if info, err := os.Stat(fm.LogPath); err == nil && info.Size()+int64(len(entry)) > fm.MaxLogSize {
+			os.Rename(fm.LogPath, fm.LogPath+".1")
+		}
+	}
 	
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(fm.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return
 	}
@@ -360,11 +1311,25 @@ func (fm *FileManager) writeLogEntry(operation Operation) {
 }
 
 func (fm *FileManager) GetOperations() []Operation {
-	return fm.operations
+	fm.opMu.Lock()
+	defer fm.opMu.Unlock()
+	return fm.operations.Entries()
+}
+
+func (fm *FileManager) OperationCount() int {
+	fm.opMu.Lock()
+	defer fm.opMu.Unlock()
+	return fm.operations.Len()
+}
+
+func (fm *FileManager) SetMaxOperations(max int) {
+	fm.opMu.Lock()
+	defer fm.opMu.Unlock()
+	fm.operations.SetMax(max)
 }
 
 func (fm *FileManager) ExportOperations() ([]byte, error) {
-	return json.MarshalIndent(fm.operations, "", "  ")
+	return json.MarshalIndent(fm.GetOperations(), "", "  ")
 }
 
 func main() {
@@ -373,14 +1338,22 @@ func main() {
 		fmt.Println("Commands:")
 		fmt.Println("  read <path> - Read file")
 		fmt.Println("  write <path> <content> - Write file")
+		fmt.Println("  append <path> <line> - Append a line to file")
 		fmt.Println("  copy <source> <destination> - Copy file")
+		fmt.Println("  copydir <source> <destination> - Copy directory tree")
 		fmt.Println("  move <source> <destination> - Move file")
 		fmt.Println("  delete <path> - Delete file")
+		fmt.Println("  rmdir <path> - Delete directory tree")
 		fmt.Println("  mkdir <path> - Create directory")
 		fmt.Println("  list <path> - List directory")
 		fmt.Println("  search <query> [root_path] - Search files")
 		fmt.Println("  info <path> - Get file info")
+		fmt.Println("  du <path> - Get total size of a directory")
 		fmt.Println("  upload <filename> <content> - Upload file")
+		fmt.Println("  compress <source> <destination> - Gzip a file")
+		fmt.Println("  decompress <source> <destination> - Gunzip a file")
+		fmt.Println("  duplicates [root_path] - Find duplicate files")
+		fmt.Println("  dedupe <oldest|newest> [root_path] - Delete duplicate files")
 		fmt.Println("  operations - Show operations")
 		fmt.Println("  export - Export operations")
 		return
@@ -424,6 +1397,19 @@ func main() {
 		} else {
 			fmt.Println("File written successfully")
 		}
+	
+	case "append":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: append <path> <line>")
+			return
+		}
+		
+		err := fm.AppendLine(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error appending to file: %v\n", err)
+		} else {
+			fmt.Println("Line appended successfully")
+		}
 		
 	case "copy":
 		if len(os.Args) < 4 {
@@ -441,6 +1427,19 @@ func main() {
 			fmt.Println("File copied successfully")
 		}
 		
+	case "copydir":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: copydir <source> <destination>")
+			return
+		}
+		
+		err := fm.CopyDir(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error copying directory: %v\n", err)
+		} else {
+			fmt.Println("Directory copied successfully")
+		}
+	
 	case "move":
 		if len(os.Args) < 4 {
 			fmt.Println("Usage: move <source> <destination>")
@@ -471,6 +1470,19 @@ func main() {
 		} else {
 			fmt.Println("File deleted successfully")
 		}
+	
+	case "rmdir":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: rmdir <path>")
+			return
+		}
+		
+		err := fm.DeleteDir(os.Args[2])
+		if err != nil {
+			fmt.Printf("Error deleting directory: %v\n", err)
+		} else {
+			fmt.Println("Directory deleted successfully")
+		}
 		
 	case "mkdir":
 		if len(os.Args) < 3 {
@@ -542,6 +1554,19 @@ func main() {
 			fmt.Println(string(infoJSON))
 		}
 		
+	case "du":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: du <path>")
+			return
+		}
+		
+		size, fileCount, err := fm.DirSize(os.Args[2])
+		if err != nil {
+			fmt.Printf("Error calculating directory size: %v\n", err)
+		} else {
+			fmt.Printf("%d bytes in %d files\n", size, fileCount)
+		}
+	
 	case "upload":
 		if len(os.Args) < 4 {
 			fmt.Println("Usage: upload <filename> <content>")
@@ -558,6 +1583,69 @@ func main() {
 			fmt.Println("File uploaded successfully")
 		}
 		
+	case "compress":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: compress <source> <destination>")
+			return
+		}
+		
+		err := fm.CompressFile(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error compressing file: %v\n", err)
+		} else {
+			fmt.Println("File compressed successfully")
+		}
+	
+	case "decompress":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: decompress <source> <destination>")
+			return
+		}
+		
+		err := fm.DecompressFile(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error decompressing file: %v\n", err)
+		} else {
+			fmt.Println("File decompressed successfully")
+		}
+	
+	case "duplicates":
+		rootPath := "."
+		if len(os.Args) > 2 {
+			rootPath = os.Args[2]
+		}
+		
+		duplicates, err := fm.FindDuplicates(rootPath)
+		if err != nil {
+			fmt.Printf("Error finding duplicates: %v\n", err)
+		} else {
+			fmt.Printf("Found %d duplicate groups:\n", len(duplicates))
+			for hash, group := range duplicates {
+				fmt.Printf("  %s\n", hash)
+				for _, file := range group {
+					fmt.Printf("    %s\n", file.Path)
+				}
+			}
+		}
+	
+	case "dedupe":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: dedupe <oldest|newest> [root_path]")
+			return
+		}
+		
+		rootPath := "."
+		if len(os.Args) > 3 {
+			rootPath = os.Args[3]
+		}
+		
+		deleted, err := fm.DeleteDuplicates(rootPath, KeepStrategy(os.Args[2]))
+		if err != nil {
+			fmt.Printf("Error deleting duplicates: %v\n", err)
+		} else {
+			fmt.Printf("Deleted %d duplicate files\n", len(deleted))
+		}
+	
 	case "operations":
 		operations := fm.GetOperations()
 		fmt.Printf("Total operations: %d\n", len(operations))
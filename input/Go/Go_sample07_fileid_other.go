@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func fileIdentity(fullPath string, info os.FileInfo) fileKey {
+	if resolved, err := filepath.EvalSymlinks(fullPath); err == nil {
+		return fileKey{path: resolved}
+	}
+	return fileKey{path: fullPath}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileIdentity(fullPath string, info os.FileInfo) fileKey {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+	}
+	return fileKey{path: fullPath}
+}
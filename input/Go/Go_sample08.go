@@ -1,45 +1,198 @@
 package main
 
 import (
-	"bufio"
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
-	"unsafe"
+	
+	"github.com/gorilla/mux"
+	
+	"go-security-scan/ringlog"
+)
+
+var (
+	ErrBlockNotFound     = errors.New("block not found")
+	ErrBlockFreed        = errors.New("block already freed")
+	ErrReadOnlyBlock     = errors.New("block is a read-only file mapping")
+	ErrOutOfBounds       = errors.New("access out of bounds")
+	ErrTooManyBlocks     = errors.New("too many blocks allocated")
+	ErrReservationClosed = errors.New("reservation already committed or released")
 )
 
+const defaultMaxOperations = 10000
+
 type MemoryManager struct {
-	blocks     map[string]*MemoryBlock
-	mutex      sync.RWMutex
-	allocated  int64
-	maxSize    int64
-	blockCount int
+	blocks      map[string]*MemoryBlock
+	store       BlockStore
+	mutex       sync.RWMutex
+	allocated   int64
+	reserved    int64
+	mapped      int64
+	maxSize     int64
+	blockCount  int
+	maxBlocks   int
+	ageWarning  time.Duration
+	secureWipe  bool
+	opMu        sync.Mutex
+	operations  *ringlog.Log[MemoryOperation]
+	subMu       sync.Mutex
+	subscribers map[<-chan MemoryOperation]chan MemoryOperation
+}
+
+type BlockStore interface {
+	Alloc(blockID string, size int) error
+	Read(blockID string, offset int, buf []byte) error
+	Write(blockID string, offset int, data []byte) error
+	Free(blockID string) error
+}
+
+type HeapStore struct {
+	mutex sync.RWMutex
+	data  map[string][]byte
+}
+
+func NewHeapStore() *HeapStore {
+	return &HeapStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (hs *HeapStore) Alloc(blockID string, size int) error {
+	data := make([]byte, size)
+	
+	if _, err := rand.Read(data); err != nil {
+		return fmt.Errorf("failed to initialize memory: %v", err)
+	}
+	
+	hs.mutex.Lock()
+	hs.data[blockID] = data
+	hs.mutex.Unlock()
+	
+	return nil
+}
+
+func (hs *HeapStore) Read(blockID string, offset int, buf []byte) error {
+	hs.mutex.RLock()
+	defer hs.mutex.RUnlock()
+	
+	data, exists := hs.data[blockID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+	}
+	
+	if offset < 0 || offset+len(buf) > len(data) {
+		return fmt.Errorf("%w: read offset=%d, length=%d, data_size=%d", ErrOutOfBounds, offset, len(buf), len(data))
+	}
+	
+	copy(buf, data[offset:])
+	return nil
+}
+
+func (hs *HeapStore) Write(blockID string, offset int, src []byte) error {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	
+	data, exists := hs.data[blockID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+	}
+	
+	if offset < 0 || offset+len(src) > len(data) {
+		return fmt.Errorf("%w: write offset=%d, data_length=%d, block_size=%d", ErrOutOfBounds, offset, len(src), len(data))
+	}
+	
+	copy(data[offset:], src)
+	return nil
+}
+
+func (hs *HeapStore) Free(blockID string) error {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	
+	if _, exists := hs.data[blockID]; !exists {
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+	}
+	
+	delete(hs.data, blockID)
+	return nil
 }
 
 type MemoryBlock struct {
 	ID        string    `json:"id"`
-	Data      []byte    `json:"data"`
+	Data      []byte    `json:"data,omitempty"`
 	Size      int       `json:"size"`
 	Allocated time.Time `json:"allocated"`
 	Accessed  time.Time `json:"accessed"`
 	Freed     bool      `json:"freed"`
+	Source    string    `json:"source,omitempty"`
+	mapped    bool
+	lock      sync.Mutex
+}
+
+func lockBlockPair(a, b *MemoryBlock) func() {
+	if a == b {
+		a.lock.Lock()
+		return a.lock.Unlock
+	}
+	
+	first, second := a, b
+	if second.ID < first.ID {
+		first, second = second, first
+	}
+	
+	first.lock.Lock()
+	second.lock.Lock()
+	return func() {
+		second.lock.Unlock()
+		first.lock.Unlock()
+	}
 }
 
 type MemoryStats struct {
 	TotalAllocated int64  `json:"total_allocated"`
+	Reserved       int64  `json:"reserved"`
+	MappedBytes    int64  `json:"mapped_bytes"`
 	MaxSize        int64  `json:"max_size"`
 	BlockCount     int    `json:"block_count"`
+	MaxBlocks      int    `json:"max_blocks"`
 	FreeMemory     uint64 `json:"free_memory"`
 	TotalMemory    uint64 `json:"total_memory"`
 }
 
+type LeakedBlock struct {
+	ID        string        `json:"id"`
+	Size      int           `json:"size"`
+	Allocated time.Time     `json:"allocated"`
+	Accessed  time.Time     `json:"accessed"`
+	IdleFor   time.Duration `json:"idle_for"`
+}
+
+type SizeBucket struct {
+	MinSize int `json:"min_size"`
+	MaxSize int `json:"max_size"`
+	Count   int `json:"count"`
+}
+
+type MemoryLeakReport struct {
+	GeneratedAt   time.Time     `json:"generated_at"`
+	IdleThreshold time.Duration `json:"idle_threshold"`
+	IdleBlocks    []LeakedBlock `json:"idle_blocks"`
+	Histogram     []SizeBucket  `json:"histogram"`
+}
+
 type MemoryOperation struct {
 	Type      string    `json:"type"`
 	BlockID   string    `json:"block_id"`
@@ -49,45 +202,186 @@ type MemoryOperation struct {
 }
 
 func NewMemoryManager(maxSize int64) *MemoryManager {
+	return NewMemoryManagerWithStore(maxSize, nil)
+}
+
+func NewMemoryManagerWithStore(maxSize int64, store BlockStore) *MemoryManager {
+	if store == nil {
+		store = NewHeapStore()
+	}
+	
 	return &MemoryManager{
-		blocks:    make(map[string]*MemoryBlock),
-		maxSize:   maxSize,
-		allocated: 0,
+		blocks:      make(map[string]*MemoryBlock),
+		store:       store,
+		maxSize:     maxSize,
+		allocated:   0,
+		operations:  ringlog.New[MemoryOperation](defaultMaxOperations),
+		subscribers: make(map[<-chan MemoryOperation]chan MemoryOperation),
 	}
 }
 
 func (mm *MemoryManager) AllocateMemory(blockID string, size int) (*MemoryBlock, error) {
+	reservation, err := mm.Reserve(size)
+	if err != nil {
+		return nil, err
+	}
+	
+	block, err := reservation.Commit(blockID)
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
+	return block, nil
+}
+
+// Reservation holds capacity set aside by Reserve until it is committed to a
+// block or released.
+type Reservation struct {
+	mm   *MemoryManager
+	size int
+	done bool
+}
+
+func (mm *MemoryManager) Available() int64 {
+	mm.mutex.RLock()
+	defer mm.mutex.RUnlock()
+	return mm.availableLocked()
+}
+
+func (mm *MemoryManager) availableLocked() int64 {
+	return mm.maxSize - mm.allocated - mm.reserved
+}
+
+func (mm *MemoryManager) CanAllocate(size int) bool {
+	return size > 0 && int64(size) <= mm.Available()
+}
+
+func (mm *MemoryManager) Reserve(size int) (*Reservation, error) {
 	if size <= 0 {
 		return nil, fmt.Errorf("invalid size: %d", size)
 	}
 	
-	if mm.allocated+int64(size) > mm.maxSize {
-		return nil, fmt.Errorf("insufficient memory: requested %d, available %d", size, mm.maxSize-mm.allocated)
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	
+	if available := mm.availableLocked(); int64(size) > available {
+		return nil, fmt.Errorf("insufficient memory: requested %d, available %d", size, available)
 	}
 	
-	data := make([]byte, size)
+	mm.reserved += int64(size)
+	return &Reservation{mm: mm, size: size}, nil
+}
+
+// Commit allocates a block of the reserved size. If it fails the reservation
+// is still held and may be committed again or released.
+func (r *Reservation) Commit(blockID string) (*MemoryBlock, error) {
+	mm := r.mm
 	
-	_, err := rand.Read(data)
+	mm.mutex.Lock()
+	if r.done {
+		mm.mutex.Unlock()
+		return nil, ErrReservationClosed
+	}
+	r.done = true
+	mm.mutex.Unlock()
+	
+	block, err := mm.commitReservation(r, blockID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize memory: %v", err)
+		mm.mutex.Lock()
+		r.done = false
+		mm.mutex.Unlock()
+		return nil, err
+	}
+	
+	mm.logOperation("allocate", blockID, r.size, fmt.Sprintf("Allocated %d bytes", r.size))
+	
+	return block, nil
+}
+
+func (mm *MemoryManager) commitReservation(r *Reservation, blockID string) (*MemoryBlock, error) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	
+	if block, exists := mm.blocks[blockID]; exists && !block.Freed {
+		return nil, fmt.Errorf("block already exists: %s", blockID)
+	}
+	if err := mm.checkBlockLimitLocked(); err != nil {
+		return nil, err
+	}
+	
+	if err := mm.store.Alloc(blockID, r.size); err != nil {
+		return nil, err
+	}
+	
+	block := &MemoryBlock{
+		ID:        blockID,
+		Size:      r.size,
+		Allocated: time.Now(),
+		Accessed:  time.Now(),
+		Freed:     false,
+	}
+	
+	mm.blocks[blockID] = block
+	mm.reserved -= int64(r.size)
+	mm.allocated += int64(r.size)
+	mm.blockCount++
+	
+	return block, nil
+}
+
+func (r *Reservation) Release() {
+	r.mm.mutex.Lock()
+	defer r.mm.mutex.Unlock()
+	
+	if r.done {
+		return
+	}
+	r.done = true
+	r.mm.reserved -= int64(r.size)
+}
+
+func (mm *MemoryManager) AllocateFromFile(blockID, path string) (*MemoryBlock, error) {
+	mm.mutex.RLock()
+	_, exists := mm.blocks[blockID]
+	mm.mutex.RUnlock()
+	
+	if exists {
+		return nil, fmt.Errorf("block already exists: %s", blockID)
+	}
+	
+	data, err := mmapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map file %s: %v", path, err)
 	}
 	
 	block := &MemoryBlock{
 		ID:        blockID,
 		Data:      data,
-		Size:      size,
+		Size:      len(data),
 		Allocated: time.Now(),
 		Accessed:  time.Now(),
 		Freed:     false,
+		Source:    path,
+		mapped:    true,
 	}
 	
 	mm.mutex.Lock()
+	if _, exists := mm.blocks[blockID]; exists {
+		mm.mutex.Unlock()
+		munmapFile(data)
+		return nil, fmt.Errorf("block already exists: %s", blockID)
+	}
+	if err := mm.checkBlockLimitLocked(); err != nil {
+		mm.mutex.Unlock()
+		munmapFile(data)
+		return nil, err
+	}
 	mm.blocks[blockID] = block
-	mm.allocated += int64(size)
+	mm.mapped += int64(block.Size)
 	mm.blockCount++
 	mm.mutex.Unlock()
 	
-	mm.logOperation("allocate", blockID, size, fmt.Sprintf("Allocated %d bytes", size))
+	mm.logOperation("map", blockID, block.Size, fmt.Sprintf("Mapped %d bytes from %s", block.Size, path))
 	
 	return block, nil
 }
@@ -98,19 +392,24 @@ func (mm *MemoryManager) ReadMemory(blockID string, offset, length int) ([]byte,
 	mm.mutex.RUnlock()
 	
 	if !exists {
-		return nil, fmt.Errorf("block not found: %s", blockID)
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
 	}
 	
+	block.lock.Lock()
+	defer block.lock.Unlock()
+	
 	if block.Freed {
-		return nil, fmt.Errorf("block already freed: %s", blockID)
+		return nil, fmt.Errorf("%w: %s", ErrBlockFreed, blockID)
 	}
 	
-	if offset < 0 || length < 0 || offset+length > len(block.Data) {
-		return nil, fmt.Errorf("invalid read: offset=%d, length=%d, data_size=%d", offset, length, len(block.Data))
+	if offset < 0 || length < 0 || offset+length > block.Size {
+		return nil, fmt.Errorf("%w: read offset=%d, length=%d, data_size=%d", ErrOutOfBounds, offset, length, block.Size)
 	}
 	
 	result := make([]byte, length)
-	copy(result, block.Data[offset:offset+length])
+	if err := mm.readBlock(block, offset, result); err != nil {
+		return nil, err
+	}
 	
 	block.Accessed = time.Now()
 	
@@ -125,18 +424,27 @@ func (mm *MemoryManager) WriteMemory(blockID string, offset int, data []byte) er
 	mm.mutex.RUnlock()
 	
 	if !exists {
-		return fmt.Errorf("block not found: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
 	}
 	
+	block.lock.Lock()
+	defer block.lock.Unlock()
+	
 	if block.Freed {
-		return fmt.Errorf("block already freed: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockFreed, blockID)
 	}
 	
-	if offset < 0 || offset+len(data) > len(block.Data) {
-		return fmt.Errorf("invalid write: offset=%d, data_length=%d, block_size=%d", offset, len(data), len(block.Data))
+	if block.mapped {
+		return fmt.Errorf("%w: %s", ErrReadOnlyBlock, blockID)
 	}
 	
-	copy(block.Data[offset:], data)
+	if offset < 0 || offset+len(data) > block.Size {
+		return fmt.Errorf("%w: write offset=%d, data_length=%d, block_size=%d", ErrOutOfBounds, offset, len(data), block.Size)
+	}
+	
+	if err := mm.store.Write(blockID, offset, data); err != nil {
+		return err
+	}
 	
 	block.Accessed = time.Now()
 	
@@ -147,26 +455,142 @@ func (mm *MemoryManager) WriteMemory(blockID string, offset int, data []byte) er
 
 func (mm *MemoryManager) FreeMemory(blockID string) error {
 	mm.mutex.Lock()
+	size, err := mm.freeLocked(blockID)
+	mm.mutex.Unlock()
+	
+	if err != nil {
+		return err
+	}
+	
+	mm.logOperation("free", blockID, size, fmt.Sprintf("Freed %d bytes", size))
+	
+	return nil
+}
+
+func (mm *MemoryManager) freeLocked(blockID string) (int, error) {
 	block, exists := mm.blocks[blockID]
 	if !exists {
-		mm.mutex.Unlock()
-		return fmt.Errorf("block not found: %s", blockID)
+		return 0, fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
 	}
 	
+	block.lock.Lock()
+	defer block.lock.Unlock()
+	
 	if block.Freed {
-		mm.mutex.Unlock()
-		return fmt.Errorf("block already freed: %s", blockID)
+		return 0, fmt.Errorf("%w: %s", ErrBlockFreed, blockID)
+	}
+	
+	if block.mapped {
+		if err := munmapFile(block.Data); err != nil {
+			return 0, fmt.Errorf("failed to unmap block %s: %v", blockID, err)
+		}
+		block.Data = nil
+		mm.mapped -= int64(block.Size)
+	} else {
+		if mm.secureWipe {
+			if err := mm.store.Write(blockID, 0, make([]byte, block.Size)); err != nil {
+				return 0, fmt.Errorf("failed to wipe block %s: %v", blockID, err)
+			}
+		}
+		if err := mm.store.Free(blockID); err != nil {
+			return 0, err
+		}
+		mm.allocated -= int64(block.Size)
 	}
 	
 	block.Freed = true
-	mm.allocated -= int64(block.Size)
 	mm.blockCount--
 	
+	return block.Size, nil
+}
+
+func (mm *MemoryManager) AllocateBatch(requests []AllocateRequest) ([]*MemoryBlock, error) {
+	mm.mutex.Lock()
+	
+	var total int64
+	seen := make(map[string]bool, len(requests))
+	for _, req := range requests {
+		if req.Size <= 0 {
+			mm.mutex.Unlock()
+			return nil, fmt.Errorf("invalid size for block %s: %d", req.ID, req.Size)
+		}
+		if seen[req.ID] {
+			mm.mutex.Unlock()
+			return nil, fmt.Errorf("duplicate block ID in batch: %s", req.ID)
+		}
+		if block, exists := mm.blocks[req.ID]; exists && !block.Freed {
+			mm.mutex.Unlock()
+			return nil, fmt.Errorf("block already exists: %s", req.ID)
+		}
+		seen[req.ID] = true
+		total += int64(req.Size)
+	}
+	
+	if available := mm.availableLocked(); total > available {
+		mm.mutex.Unlock()
+		return nil, fmt.Errorf("insufficient memory for batch: requested %d, available %d", total, available)
+	}
+	
+	if mm.maxBlocks > 0 && mm.blockCount+len(requests) > mm.maxBlocks {
+		mm.mutex.Unlock()
+		return nil, fmt.Errorf("%w: limit is %d", ErrTooManyBlocks, mm.maxBlocks)
+	}
+	
+	blocks := make([]*MemoryBlock, 0, len(requests))
+	for _, req := range requests {
+		if err := mm.store.Alloc(req.ID, req.Size); err != nil {
+			for _, allocated := range blocks {
+				mm.store.Free(allocated.ID)
+			}
+			mm.mutex.Unlock()
+			return nil, fmt.Errorf("batch allocation failed at block %s: %v", req.ID, err)
+		}
+		
+		now := time.Now()
+		blocks = append(blocks, &MemoryBlock{
+			ID:        req.ID,
+			Size:      req.Size,
+			Allocated: now,
+			Accessed:  now,
+		})
+	}
+	
+	for _, block := range blocks {
+		mm.blocks[block.ID] = block
+	}
+	mm.allocated += total
+	mm.blockCount += len(blocks)
+	
 	mm.mutex.Unlock()
 	
-	mm.logOperation("free", blockID, block.Size, fmt.Sprintf("Freed %d bytes", block.Size))
+	mm.logOperation("allocate_batch", fmt.Sprintf("%d blocks", len(blocks)), int(total), fmt.Sprintf("Allocated %d bytes across %d blocks", total, len(blocks)))
 	
-	return nil
+	return blocks, nil
+}
+
+func (mm *MemoryManager) FreeBatch(ids []string) ([]error, error) {
+	results := make([]error, len(ids))
+	freedBytes := 0
+	failed := 0
+	
+	mm.mutex.Lock()
+	for i, id := range ids {
+		size, err := mm.freeLocked(id)
+		results[i] = err
+		if err != nil {
+			failed++
+			continue
+		}
+		freedBytes += size
+	}
+	mm.mutex.Unlock()
+	
+	mm.logOperation("free_batch", fmt.Sprintf("%d blocks", len(ids)-failed), freedBytes, fmt.Sprintf("Freed %d bytes, %d failures", freedBytes, failed))
+	
+	if failed > 0 {
+		return results, fmt.Errorf("failed to free %d of %d blocks", failed, len(ids))
+	}
+	return results, nil
 }
 
 func (mm *MemoryManager) ResizeMemory(blockID string, newSize int) error {
@@ -174,12 +598,20 @@ func (mm *MemoryManager) ResizeMemory(blockID string, newSize int) error {
 	block, exists := mm.blocks[blockID]
 	if !exists {
 		mm.mutex.Unlock()
-		return fmt.Errorf("block not found: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
 	}
 	
+	block.lock.Lock()
+	defer block.lock.Unlock()
+	
 	if block.Freed {
 		mm.mutex.Unlock()
-		return fmt.Errorf("block already freed: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockFreed, blockID)
+	}
+	
+	if block.mapped {
+		mm.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrReadOnlyBlock, blockID)
 	}
 	
 	if newSize <= 0 {
@@ -188,15 +620,48 @@ func (mm *MemoryManager) ResizeMemory(blockID string, newSize int) error {
 	}
 	
 	sizeDiff := newSize - block.Size
-	if mm.allocated+int64(sizeDiff) > mm.maxSize {
+	if available := mm.availableLocked(); int64(sizeDiff) > available {
+		mm.mutex.Unlock()
+		return fmt.Errorf("insufficient memory for resize: requested %d, available %d", sizeDiff, available)
+	}
+	
+	oldData := make([]byte, block.Size)
+	if err := mm.store.Read(blockID, 0, oldData); err != nil {
 		mm.mutex.Unlock()
-		return fmt.Errorf("insufficient memory for resize: requested %d, available %d", sizeDiff, mm.maxSize-mm.allocated)
+		return err
+	}
+	
+	if mm.secureWipe {
+		if err := mm.store.Write(blockID, 0, make([]byte, block.Size)); err != nil {
+			wipeBytes(oldData)
+			mm.mutex.Unlock()
+			return fmt.Errorf("failed to wipe block %s: %v", blockID, err)
+		}
+	}
+	
+	if err := mm.store.Alloc(blockID, newSize); err != nil {
+		if mm.secureWipe {
+			mm.store.Write(blockID, 0, oldData)
+			wipeBytes(oldData)
+		}
+		mm.mutex.Unlock()
+		return err
 	}
 	
 	newData := make([]byte, newSize)
-	copy(newData, block.Data)
+	copy(newData, oldData)
+	err := mm.store.Write(blockID, 0, newData)
+	
+	if mm.secureWipe {
+		wipeBytes(oldData)
+		wipeBytes(newData)
+	}
+	
+	if err != nil {
+		mm.mutex.Unlock()
+		return err
+	}
 	
-	block.Data = newData
 	block.Size = newSize
 	mm.allocated += int64(sizeDiff)
 	
@@ -207,6 +672,99 @@ func (mm *MemoryManager) ResizeMemory(blockID string, newSize int) error {
 	return nil
 }
 
+// readBlock must be called with block.lock held so a concurrent free cannot
+// unmap the data mid-copy.
+func (mm *MemoryManager) readBlock(block *MemoryBlock, offset int, buf []byte) error {
+	if block.mapped {
+		copy(buf, block.Data[offset:])
+		return nil
+	}
+	return mm.store.Read(block.ID, offset, buf)
+}
+
+type blockAccessor struct {
+	mm    *MemoryManager
+	block *MemoryBlock
+}
+
+func (mm *MemoryManager) ReaderAt(blockID string) (io.ReaderAt, error) {
+	return mm.accessor(blockID)
+}
+
+func (mm *MemoryManager) WriterAt(blockID string) (io.WriterAt, error) {
+	accessor, err := mm.accessor(blockID)
+	if err != nil {
+		return nil, err
+	}
+	if accessor.block.mapped {
+		return nil, fmt.Errorf("%w: %s", ErrReadOnlyBlock, blockID)
+	}
+	return accessor, nil
+}
+
+func (mm *MemoryManager) accessor(blockID string) (*blockAccessor, error) {
+	mm.mutex.RLock()
+	block, exists := mm.blocks[blockID]
+	mm.mutex.RUnlock()
+	
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+	}
+	
+	return &blockAccessor{mm: mm, block: block}, nil
+}
+
+func (a *blockAccessor) ReadAt(p []byte, off int64) (int, error) {
+	a.block.lock.Lock()
+	defer a.block.lock.Unlock()
+	
+	if a.block.Freed {
+		return 0, fmt.Errorf("%w: %s", ErrBlockFreed, a.block.ID)
+	}
+	
+	if off < 0 {
+		return 0, fmt.Errorf("%w: read offset=%d", ErrOutOfBounds, off)
+	}
+	if off >= int64(a.block.Size) {
+		return 0, io.EOF
+	}
+	
+	n := len(p)
+	if remaining := a.block.Size - int(off); n > remaining {
+		n = remaining
+	}
+	
+	if err := a.mm.readBlock(a.block, int(off), p[:n]); err != nil {
+		return 0, err
+	}
+	a.block.Accessed = time.Now()
+	
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (a *blockAccessor) WriteAt(p []byte, off int64) (int, error) {
+	a.block.lock.Lock()
+	defer a.block.lock.Unlock()
+	
+	if a.block.Freed {
+		return 0, fmt.Errorf("%w: %s", ErrBlockFreed, a.block.ID)
+	}
+	
+	if off < 0 || off+int64(len(p)) > int64(a.block.Size) {
+		return 0, fmt.Errorf("%w: write offset=%d, data_length=%d, block_size=%d", ErrOutOfBounds, off, len(p), a.block.Size)
+	}
+	
+	if err := a.mm.store.Write(a.block.ID, int(off), p); err != nil {
+		return 0, err
+	}
+	a.block.Accessed = time.Now()
+	
+	return len(p), nil
+}
+
 func (mm *MemoryManager) GetMemoryStats() *MemoryStats {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -214,8 +772,11 @@ func (mm *MemoryManager) GetMemoryStats() *MemoryStats {
 	mm.mutex.RLock()
 	stats := &MemoryStats{
 		TotalAllocated: mm.allocated,
+		Reserved:       mm.reserved,
+		MappedBytes:    mm.mapped,
 		MaxSize:        mm.maxSize,
 		BlockCount:     mm.blockCount,
+		MaxBlocks:      mm.maxBlocks,
 		FreeMemory:     m.Frees,
 		TotalMemory:    m.TotalAlloc,
 	}
@@ -224,6 +785,113 @@ func (mm *MemoryManager) GetMemoryStats() *MemoryStats {
 	return stats
 }
 
+// SetSecureWipe zeroes block contents on free and resize. This is best-effort:
+// copies returned by ReadMemory are not wiped, and the OS may already have
+// paged the bytes out to swap.
+func (mm *MemoryManager) SetSecureWipe(enabled bool) {
+	mm.mutex.Lock()
+	mm.secureWipe = enabled
+	mm.mutex.Unlock()
+}
+
+func wipeBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
+
+func (mm *MemoryManager) SetMaxBlocks(max int) {
+	mm.mutex.Lock()
+	mm.maxBlocks = max
+	mm.mutex.Unlock()
+}
+
+// checkBlockLimitLocked must be called with mm.mutex held for writing, in the
+// same critical section that inserts the block.
+func (mm *MemoryManager) checkBlockLimitLocked() error {
+	if mm.maxBlocks > 0 && mm.blockCount >= mm.maxBlocks {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyBlocks, mm.maxBlocks)
+	}
+	return nil
+}
+
+func (mm *MemoryManager) SetAgeWarning(threshold time.Duration) {
+	mm.mutex.Lock()
+	mm.ageWarning = threshold
+	mm.mutex.Unlock()
+}
+
+func (mm *MemoryManager) LeakReport(idleThreshold time.Duration) *MemoryLeakReport {
+	now := time.Now()
+	report := &MemoryLeakReport{
+		GeneratedAt:   now,
+		IdleThreshold: idleThreshold,
+		IdleBlocks:    make([]LeakedBlock, 0),
+	}
+	
+	buckets := make(map[int]int)
+	maxMagnitude := 0
+	
+	mm.mutex.RLock()
+	for _, block := range mm.blocks {
+		block.lock.Lock()
+		if block.Freed {
+			block.lock.Unlock()
+			continue
+		}
+		
+		idleFor := now.Sub(block.Accessed)
+		if idleFor >= idleThreshold {
+			report.IdleBlocks = append(report.IdleBlocks, LeakedBlock{
+				ID:        block.ID,
+				Size:      block.Size,
+				Allocated: block.Allocated,
+				Accessed:  block.Accessed,
+				IdleFor:   idleFor,
+			})
+		}
+		
+		if mm.ageWarning > 0 && now.Sub(block.Allocated) > mm.ageWarning {
+			log.Printf("Warning: block %s (%d bytes) has been allocated for %v", block.ID, block.Size, now.Sub(block.Allocated))
+		}
+		
+		magnitude := sizeMagnitude(block.Size)
+		block.lock.Unlock()
+		buckets[magnitude]++
+		if magnitude > maxMagnitude {
+			maxMagnitude = magnitude
+		}
+	}
+	mm.mutex.RUnlock()
+	
+	sort.Slice(report.IdleBlocks, func(i, j int) bool {
+		return report.IdleBlocks[i].IdleFor > report.IdleBlocks[j].IdleFor
+	})
+	
+	if len(buckets) > 0 {
+		minSize := 1
+		for magnitude := 0; magnitude <= maxMagnitude; magnitude++ {
+			report.Histogram = append(report.Histogram, SizeBucket{
+				MinSize: minSize,
+				MaxSize: minSize*10 - 1,
+				Count:   buckets[magnitude],
+			})
+			minSize *= 10
+		}
+	}
+	
+	return report
+}
+
+func sizeMagnitude(size int) int {
+	magnitude := 0
+	for size >= 10 {
+		size /= 10
+		magnitude++
+	}
+	return magnitude
+}
+
 func (mm *MemoryManager) ListBlocks() []*MemoryBlock {
 	mm.mutex.RLock()
 	blocks := make([]*MemoryBlock, 0, len(mm.blocks))
@@ -240,11 +908,20 @@ func (mm *MemoryManager) SearchMemory(pattern []byte) []*MemoryBlock {
 	
 	mm.mutex.RLock()
 	for _, block := range mm.blocks {
+		block.lock.Lock()
 		if block.Freed {
+			block.lock.Unlock()
+			continue
+		}
+		
+		data := make([]byte, block.Size)
+		err := mm.readBlock(block, 0, data)
+		block.lock.Unlock()
+		if err != nil {
 			continue
 		}
 		
-		if bytesContains(block.Data, pattern) {
+		if bytesContains(data, pattern) {
 			results = append(results, block)
 		}
 	}
@@ -255,37 +932,46 @@ func (mm *MemoryManager) SearchMemory(pattern []byte) []*MemoryBlock {
 
 func (mm *MemoryManager) CopyMemory(sourceID, destID string, sourceOffset, destOffset, length int) error {
 	mm.mutex.RLock()
-	sourceBlock, exists := mm.blocks[sourceID]
-	if !exists {
-		mm.mutex.RUnlock()
+	sourceBlock, sourceExists := mm.blocks[sourceID]
+	destBlock, destExists := mm.blocks[destID]
+	mm.mutex.RUnlock()
+	
+	if !sourceExists {
 		return fmt.Errorf("source block not found: %s", sourceID)
 	}
 	
-	destBlock, exists := mm.blocks[destID]
-	if !exists {
-		mm.mutex.RUnlock()
+	if !destExists {
 		return fmt.Errorf("destination block not found: %s", destID)
 	}
 	
+	unlock := lockBlockPair(sourceBlock, destBlock)
+	defer unlock()
+	
 	if sourceBlock.Freed || destBlock.Freed {
-		mm.mutex.RUnlock()
 		return fmt.Errorf("block already freed")
 	}
 	
+	if destBlock.mapped {
+		return fmt.Errorf("block is a read-only file mapping: %s", destID)
+	}
+	
 	if sourceOffset < 0 || destOffset < 0 || length < 0 ||
-		sourceOffset+length > len(sourceBlock.Data) ||
-		destOffset+length > len(destBlock.Data) {
-		mm.mutex.RUnlock()
+		sourceOffset+length > sourceBlock.Size ||
+		destOffset+length > destBlock.Size {
 		return fmt.Errorf("invalid copy: source_offset=%d, dest_offset=%d, length=%d", sourceOffset, destOffset, length)
 	}
 	
-	copy(destBlock.Data[destOffset:], sourceBlock.Data[sourceOffset:sourceOffset+length])
+	buf := make([]byte, length)
+	if err := mm.readBlock(sourceBlock, sourceOffset, buf); err != nil {
+		return err
+	}
+	if err := mm.store.Write(destID, destOffset, buf); err != nil {
+		return err
+	}
 	
 	sourceBlock.Accessed = time.Now()
 	destBlock.Accessed = time.Now()
 	
-	mm.mutex.RUnlock()
-	
 	mm.logOperation("copy", fmt.Sprintf("%s->%s", sourceID, destID), length, fmt.Sprintf("Copied %d bytes", length))
 	
 	return nil
@@ -297,19 +983,31 @@ func (mm *MemoryManager) SetMemory(blockID string, offset int, value byte, count
 	mm.mutex.RUnlock()
 	
 	if !exists {
-		return fmt.Errorf("block not found: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
 	}
 	
+	block.lock.Lock()
+	defer block.lock.Unlock()
+	
 	if block.Freed {
-		return fmt.Errorf("block already freed: %s", blockID)
+		return fmt.Errorf("%w: %s", ErrBlockFreed, blockID)
 	}
 	
-	if offset < 0 || count < 0 || offset+count > len(block.Data) {
-		return fmt.Errorf("invalid set: offset=%d, count=%d, block_size=%d", offset, count, len(block.Data))
+	if block.mapped {
+		return fmt.Errorf("%w: %s", ErrReadOnlyBlock, blockID)
 	}
 	
-	for i := 0; i < count; i++ {
-		block.Data[offset+i] = value
+	if offset < 0 || count < 0 || offset+count > block.Size {
+		return fmt.Errorf("%w: set offset=%d, count=%d, block_size=%d", ErrOutOfBounds, offset, count, block.Size)
+	}
+	
+	fill := make([]byte, count)
+	for i := range fill {
+		fill[i] = value
+	}
+	
+	if err := mm.store.Write(blockID, offset, fill); err != nil {
+		return err
 	}
 	
 	block.Accessed = time.Now()
@@ -321,37 +1019,45 @@ func (mm *MemoryManager) SetMemory(blockID string, offset int, value byte, count
 
 func (mm *MemoryManager) CompareMemory(blockID1, blockID2 string, offset1, offset2, length int) (bool, error) {
 	mm.mutex.RLock()
-	block1, exists := mm.blocks[blockID1]
-	if !exists {
-		mm.mutex.RUnlock()
+	block1, exists1 := mm.blocks[blockID1]
+	block2, exists2 := mm.blocks[blockID2]
+	mm.mutex.RUnlock()
+	
+	if !exists1 {
 		return false, fmt.Errorf("block1 not found: %s", blockID1)
 	}
 	
-	block2, exists := mm.blocks[blockID2]
-	if !exists {
-		mm.mutex.RUnlock()
+	if !exists2 {
 		return false, fmt.Errorf("block2 not found: %s", blockID2)
 	}
 	
+	unlock := lockBlockPair(block1, block2)
+	defer unlock()
+	
 	if block1.Freed || block2.Freed {
-		mm.mutex.RUnlock()
 		return false, fmt.Errorf("block already freed")
 	}
 	
 	if offset1 < 0 || offset2 < 0 || length < 0 ||
-		offset1+length > len(block1.Data) ||
-		offset2+length > len(block2.Data) {
-		mm.mutex.RUnlock()
+		offset1+length > block1.Size ||
+		offset2+length > block2.Size {
 		return false, fmt.Errorf("invalid compare: offset1=%d, offset2=%d, length=%d", offset1, offset2, length)
 	}
 	
-	equal := bytesEqual(block1.Data[offset1:offset1+length], block2.Data[offset2:offset2+length])
+	data1 := make([]byte, length)
+	data2 := make([]byte, length)
+	if err := mm.readBlock(block1, offset1, data1); err != nil {
+		return false, err
+	}
+	if err := mm.readBlock(block2, offset2, data2); err != nil {
+		return false, err
+	}
+	
+	equal := bytesEqual(data1, data2)
 	
 	block1.Accessed = time.Now()
 	block2.Accessed = time.Now()
 	
-	mm.mutex.RUnlock()
-	
 	mm.logOperation("compare", fmt.Sprintf("%s-%s", blockID1, blockID2), length, fmt.Sprintf("Compared %d bytes", length))
 	
 	return equal, nil
@@ -366,11 +1072,70 @@ func (mm *MemoryManager) logOperation(opType, blockID string, size int, details
 		Details:   details,
 	}
 	
+	mm.opMu.Lock()
+	mm.operations.Append(operation)
+	mm.opMu.Unlock()
+	mm.publish(operation)
+	
 	fmt.Printf("[%s] %s: %s (size=%d) - %s\n",
 		operation.Timestamp.Format("2006-01-02 15:04:05"),
 		operation.Type, operation.BlockID, operation.Size, operation.Details)
 }
 
+func (mm *MemoryManager) Subscribe() <-chan MemoryOperation {
+	ch := make(chan MemoryOperation, 64)
+	
+	mm.subMu.Lock()
+	mm.subscribers[ch] = ch
+	mm.subMu.Unlock()
+	
+	return ch
+}
+
+func (mm *MemoryManager) Unsubscribe(sub <-chan MemoryOperation) {
+	mm.subMu.Lock()
+	defer mm.subMu.Unlock()
+	
+	if ch, ok := mm.subscribers[sub]; ok {
+		delete(mm.subscribers, sub)
+		close(ch)
+	}
+}
+
+func (mm *MemoryManager) publish(operation MemoryOperation) {
+	mm.subMu.Lock()
+	defer mm.subMu.Unlock()
+	
+	for _, ch := range mm.subscribers {
+		select {
+		case ch <- operation:
+		default:
+		}
+	}
+}
+
+func (mm *MemoryManager) GetOperations() []MemoryOperation {
+	mm.opMu.Lock()
+	defer mm.opMu.Unlock()
+	return mm.operations.Entries()
+}
+
+func (mm *MemoryManager) OperationCount() int {
+	mm.opMu.Lock()
+	defer mm.opMu.Unlock()
+	return mm.operations.Len()
+}
+
+func (mm *MemoryManager) SetMaxOperations(max int) {
+	mm.opMu.Lock()
+	defer mm.opMu.Unlock()
+	mm.operations.SetMax(max)
+}
+
+func (mm *MemoryManager) ExportOperations() ([]byte, error) {
+	return json.MarshalIndent(mm.GetOperations(), "", "  ")
+}
+
 func bytesContains(data, pattern []byte) bool {
 	return strings.Contains(string(data), string(pattern))
 }
@@ -387,21 +1152,292 @@ func bytesEqual(a, b []byte) bool {
 	return true
 }
 
+type MemoryAPIResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type AllocateRequest struct {
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+}
+
+type WriteRequest struct {
+	Offset int    `json:"offset"`
+	Data   string `json:"data"`
+}
+
+type BlockInfo struct {
+	ID        string    `json:"id"`
+	Size      int       `json:"size"`
+	Allocated time.Time `json:"allocated"`
+	Accessed  time.Time `json:"accessed"`
+	Freed     bool      `json:"freed"`
+	Source    string    `json:"source,omitempty"`
+}
+
+type ReadResponse struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	Data   string `json:"data"`
+}
+
+type MemoryServer struct {
+	mm     *MemoryManager
+	router *mux.Router
+	token  string
+}
+
+// NewMemoryServer serves mm over HTTP. Every request must carry token as a
+// bearer token; an empty token rejects all requests.
+func NewMemoryServer(mm *MemoryManager, token string) *MemoryServer {
+	server := &MemoryServer{
+		mm:     mm,
+		router: mux.NewRouter(),
+		token:  token,
+	}
+	server.setupRoutes()
+	return server
+}
+
+func (s *MemoryServer) setupRoutes() {
+	api := s.router.PathPrefix("/api").Subrouter()
+	api.Use(s.loggingMiddleware)
+	api.Use(s.jsonMiddleware)
+	api.Use(s.authMiddleware)
+	
+	api.HandleFunc("/blocks", s.listBlocks).Methods("GET")
+	api.HandleFunc("/blocks", s.allocateBlock).Methods("POST")
+	api.HandleFunc("/blocks/{id}", s.freeBlock).Methods("DELETE")
+	api.HandleFunc("/blocks/{id}/data", s.readBlock).Methods("GET")
+	api.HandleFunc("/blocks/{id}/data", s.writeBlock).Methods("PUT")
+	api.HandleFunc("/stats", s.getStats).Methods("GET")
+	api.HandleFunc("/operations", s.getOperations).Methods("GET")
+}
+
+func (s *MemoryServer) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		
+		next.ServeHTTP(recorder, r)
+		
+		log.Printf("%s %s %d %v", r.Method, r.URL.Path, recorder.statusCode, time.Since(start))
+	})
+}
+
+func (s *MemoryServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid API token")
+			return
+		}
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *MemoryServer) jsonMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rw *statusRecorder) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (s *MemoryServer) listBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks := s.mm.ListBlocks()
+	
+	infos := make([]BlockInfo, 0, len(blocks))
+	for _, block := range blocks {
+		infos = append(infos, BlockInfo{
+			ID:        block.ID,
+			Size:      block.Size,
+			Allocated: block.Allocated,
+			Accessed:  block.Accessed,
+			Freed:     block.Freed,
+			Source:    block.Source,
+		})
+	}
+	
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{Success: true, Data: infos})
+}
+
+func (s *MemoryServer) allocateBlock(w http.ResponseWriter, r *http.Request) {
+	var req AllocateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	
+	if req.ID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Block ID is required")
+		return
+	}
+	
+	block, err := s.mm.AllocateMemory(req.ID, req.Size)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	s.writeResponse(w, http.StatusCreated, MemoryAPIResponse{
+		Success: true,
+		Data:    BlockInfo{ID: block.ID, Size: block.Size, Allocated: block.Allocated, Accessed: block.Accessed},
+		Message: "Block allocated successfully",
+	})
+}
+
+func (s *MemoryServer) readBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["id"]
+	
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	length, err := strconv.Atoi(r.URL.Query().Get("length"))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid length")
+		return
+	}
+	
+	data, err := s.mm.ReadMemory(blockID, offset, length)
+	if err != nil {
+		s.writeMemoryError(w, err)
+		return
+	}
+	
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{
+		Success: true,
+		Data: ReadResponse{
+			ID:     blockID,
+			Offset: offset,
+			Length: len(data),
+			Data:   base64.StdEncoding.EncodeToString(data),
+		},
+	})
+}
+
+func (s *MemoryServer) writeBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["id"]
+	
+	var req WriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Data must be base64 encoded")
+		return
+	}
+	
+	if err := s.mm.WriteMemory(blockID, req.Offset, data); err != nil {
+		s.writeMemoryError(w, err)
+		return
+	}
+	
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Wrote %d bytes to block %s", len(data), blockID),
+	})
+}
+
+func (s *MemoryServer) freeBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["id"]
+	
+	if err := s.mm.FreeMemory(blockID); err != nil {
+		s.writeMemoryError(w, err)
+		return
+	}
+	
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{
+		Success: true,
+		Message: "Block freed successfully",
+	})
+}
+
+func (s *MemoryServer) getStats(w http.ResponseWriter, r *http.Request) {
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{Success: true, Data: s.mm.GetMemoryStats()})
+}
+
+func (s *MemoryServer) getOperations(w http.ResponseWriter, r *http.Request) {
+	s.writeResponse(w, http.StatusOK, MemoryAPIResponse{Success: true, Data: s.mm.GetOperations()})
+}
+
+func (s *MemoryServer) writeMemoryError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrBlockNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrOutOfBounds):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrBlockFreed):
+		status = http.StatusGone
+	case errors.Is(err, ErrReadOnlyBlock):
+		status = http.StatusConflict
+	}
+	s.writeErrorResponse(w, status, err.Error())
+}
+
+func (s *MemoryServer) writeResponse(w http.ResponseWriter, statusCode int, response MemoryAPIResponse) {
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *MemoryServer) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	s.writeResponse(w, statusCode, MemoryAPIResponse{Success: false, Error: message})
+}
+
+func (s *MemoryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func (s *MemoryServer) ListenAndServe(addr string) error {
+	httpServer := &http.Server{
+		Addr:         addr,
+		Handler:      s,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: go run main.go <command> [args...]")
 		fmt.Println("Commands:")
 		fmt.Println("  allocate <block_id> <size> - Allocate memory block")
+		fmt.Println("  map <block_id> <path> - Map a file into a read-only block")
 		fmt.Println("  read <block_id> <offset> <length> - Read from memory")
 		fmt.Println("  write <block_id> <offset> <data> - Write to memory")
 		fmt.Println("  free <block_id> - Free memory block")
 		fmt.Println("  resize <block_id> <new_size> - Resize memory block")
 		fmt.Println("  list - List all memory blocks")
 		fmt.Println("  stats - Show memory statistics")
+		fmt.Println("  leaks <idle_seconds> - Report idle blocks and a size histogram")
 		fmt.Println("  search <pattern> - Search memory for pattern")
 		fmt.Println("  copy <source_id> <dest_id> <source_offset> <dest_offset> <length> - Copy memory")
 		fmt.Println("  set <block_id> <offset> <value> <count> - Set memory bytes")
 		fmt.Println("  compare <block_id1> <block_id2> <offset1> <offset2> <length> - Compare memory")
+		fmt.Println("  serve [addr] - Serve the memory manager over HTTP (default 127.0.0.1:8081, requires MEMORY_API_TOKEN)")
 		return
 	}
 	
@@ -429,6 +1465,19 @@ func main() {
 		} else {
 			fmt.Printf("Allocated block %s with %d bytes\n", block.ID, block.Size)
 		}
+	
+	case "map":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: map <block_id> <path>")
+			return
+		}
+		
+		block, err := mm.AllocateFromFile(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error mapping file: %v\n", err)
+		} else {
+			fmt.Printf("Mapped block %s with %d bytes from %s\n", block.ID, block.Size, block.Source)
+		}
 		
 	case "read":
 		if len(os.Args) < 5 {
@@ -524,6 +1573,22 @@ func main() {
 		statsJSON, _ := json.MarshalIndent(stats, "", "  ")
 		fmt.Println(string(statsJSON))
 		
+	case "leaks":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: leaks <idle_seconds>")
+			return
+		}
+		
+		idleSeconds, err := strconv.Atoi(os.Args[2])
+		if err != nil {
+			fmt.Println("Invalid idle seconds")
+			return
+		}
+		
+		report := mm.LeakReport(time.Duration(idleSeconds) * time.Second)
+		reportJSON, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(reportJSON))
+	
 	case "search":
 		if len(os.Args) < 3 {
 			fmt.Println("Usage: search <pattern>")
@@ -538,6 +1603,23 @@ func main() {
 			fmt.Printf("  Block: %s, Size: %d\n", block.ID, block.Size)
 		}
 		
+	case "serve":
+		addr := "127.0.0.1:8081"
+		if len(os.Args) > 2 {
+			addr = os.Args[2]
+		}
+		
+		token := os.Getenv("MEMORY_API_TOKEN")
+		if token == "" {
+			fmt.Println("MEMORY_API_TOKEN must be set to serve the memory manager")
+			return
+		}
+		
+		fmt.Printf("Serving memory manager on %s\n", addr)
+		if err := NewMemoryServer(mm, token).ListenAndServe(addr); err != nil {
+			fmt.Printf("Server error: %v\n", err)
+		}
+	
 	default:
 		fmt.Println("Unknown command:", command)
 	}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmapFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	if len(data) == 0 {
+		return nil, fmt.Errorf("cannot map empty file")
+	}
+	
+	return data, nil
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
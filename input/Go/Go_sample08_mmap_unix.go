@@ -0,0 +1,36 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mmapFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	
+	size := info.Size()
+	if size <= 0 {
+		return nil, fmt.Errorf("cannot map empty file")
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("file too large to map: %d bytes", size)
+	}
+	
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestCopyMemoryReciprocalStress(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	for _, id := range []string{"a", "b"} {
+		if _, err := mm.AllocateMemory(id, 4096); err != nil {
+			t.Fatalf("AllocateMemory(%s): %v", id, err)
+		}
+	}
+
+	const iterations = 200
+	var wg sync.WaitGroup
+	run := func(fn func(i int) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if err := fn(i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for worker := 0; worker < 4; worker++ {
+		run(func(i int) error { return mm.CopyMemory("a", "b", i%1024, 0, 1024) })
+		run(func(i int) error { return mm.CopyMemory("b", "a", 0, i%1024, 1024) })
+	}
+	run(func(i int) error {
+		_, err := mm.ReadMemory("a", i%4096, 1)
+		return err
+	})
+	run(func(i int) error { return mm.WriteMemory("b", i%4096, []byte{byte(i)}) })
+	run(func(i int) error { return mm.SetMemory("a", 0, byte(i), 64) })
+	run(func(i int) error {
+		mm.SearchMemory([]byte{0xff, 0xfe})
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("reciprocal copies deadlocked")
+	}
+}
+
+func TestCopyMemoryRacesFree(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	for _, id := range []string{"a", "b"} {
+		if _, err := mm.AllocateMemory(id, 1024); err != nil {
+			t.Fatalf("AllocateMemory(%s): %v", id, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "a"}} {
+		pair := pair
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				mm.CopyMemory(pair[0], pair[1], 0, 0, 512)
+				mm.ReadMemory(pair[0], 0, 512)
+				mm.WriteMemory(pair[1], 0, []byte("x"))
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mm.FreeMemory("a")
+		mm.ResizeMemory("b", 2048)
+	}()
+	wg.Wait()
+
+	if _, err := mm.ReadMemory("a", 0, 1); err == nil {
+		t.Fatal("expected reading a freed block to fail")
+	}
+}
+
+func TestMemoryServerRequiresToken(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+
+	for _, tc := range []struct {
+		token, header string
+		want          int
+	}{
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"", "Bearer ", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		NewMemoryServer(mm, tc.token).ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("token %q, header %q: status %d, want %d", tc.token, tc.header, w.Code, tc.want)
+		}
+	}
+}
+
+func TestResizeWipesOldStoreBuffer(t *testing.T) {
+	store := NewHeapStore()
+	mm := NewMemoryManagerWithStore(1<<20, store)
+	mm.SetSecureWipe(true)
+
+	if _, err := mm.AllocateMemory("a", 64); err != nil {
+		t.Fatalf("AllocateMemory: %v", err)
+	}
+	if err := mm.WriteMemory("a", 0, bytes.Repeat([]byte{0xAA}, 64)); err != nil {
+		t.Fatalf("WriteMemory: %v", err)
+	}
+	old := store.data["a"]
+
+	if err := mm.ResizeMemory("a", 128); err != nil {
+		t.Fatalf("ResizeMemory: %v", err)
+	}
+	if !bytes.Equal(old, make([]byte, len(old))) {
+		t.Fatalf("old store buffer was not wiped on resize: %x", old)
+	}
+
+	data, err := mm.ReadMemory("a", 0, 64)
+	if err != nil {
+		t.Fatalf("ReadMemory: %v", err)
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte{0xAA}, 64)) {
+		t.Fatalf("resize lost block contents: %x", data)
+	}
+}
+
+func TestConcurrentAllocateRespectsMaxBlocks(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	mm.SetMaxBlocks(5)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			mm.AllocateMemory(fmt.Sprintf("block-%d", i), 16)
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(mm.ListBlocks()); got != 5 {
+		t.Fatalf("allocated %d blocks, want the limit of 5", got)
+	}
+	if stats := mm.GetMemoryStats(); stats.BlockCount != 5 {
+		t.Fatalf("block count is %d, want 5", stats.BlockCount)
+	}
+}
+
+func TestFreeWipesStoreBuffer(t *testing.T) {
+	store := NewHeapStore()
+	mm := NewMemoryManagerWithStore(1<<20, store)
+	mm.SetSecureWipe(true)
+
+	if _, err := mm.AllocateMemory("a", 64); err != nil {
+		t.Fatalf("AllocateMemory: %v", err)
+	}
+	if err := mm.WriteMemory("a", 0, bytes.Repeat([]byte{0xAA}, 64)); err != nil {
+		t.Fatalf("WriteMemory: %v", err)
+	}
+	data := store.data["a"]
+
+	if err := mm.FreeMemory("a"); err != nil {
+		t.Fatalf("FreeMemory: %v", err)
+	}
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Fatalf("freed store buffer was not wiped: %x", data)
+	}
+}
+
+func newPatternBlock(t testing.TB, mm *MemoryManager, id string, size int) []byte {
+	t.Helper()
+
+	if _, err := mm.AllocateMemory(id, size); err != nil {
+		t.Fatalf("AllocateMemory: %v", err)
+	}
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+
+	w, err := mm.WriterAt(id)
+	if err != nil {
+		t.Fatalf("WriterAt: %v", err)
+	}
+	if n, err := w.WriteAt(content, 0); err != nil || n != size {
+		t.Fatalf("WriteAt = %d, %v", n, err)
+	}
+	return content
+}
+
+func TestReaderAtSectionReader(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	content := newPatternBlock(t, mm, "a", 10000)
+
+	r, err := mm.ReaderAt("a")
+	if err != nil {
+		t.Fatalf("ReaderAt: %v", err)
+	}
+	if err := iotest.TestReader(io.NewSectionReader(r, 0, int64(len(content))), content); err != nil {
+		t.Fatal(err)
+	}
+	if err := iotest.TestReader(io.NewSectionReader(r, 1234, 4000), content[1234:5234]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaderAtBounds(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	content := newPatternBlock(t, mm, "a", 100)
+
+	r, err := mm.ReaderAt("a")
+	if err != nil {
+		t.Fatalf("ReaderAt: %v", err)
+	}
+
+	buf := make([]byte, 30)
+	n, err := r.ReadAt(buf, 90)
+	if n != 10 || err != io.EOF || !bytes.Equal(buf[:n], content[90:]) {
+		t.Fatalf("ReadAt across the end = %d, %v; want 10, EOF", n, err)
+	}
+	if n, err := r.ReadAt(buf, 100); n != 0 || err != io.EOF {
+		t.Fatalf("ReadAt at the end = %d, %v; want 0, EOF", n, err)
+	}
+	if _, err := r.ReadAt(buf, -1); !errors.Is(err, ErrOutOfBounds) {
+		t.Fatalf("ReadAt(-1) = %v, want ErrOutOfBounds", err)
+	}
+
+	if err := mm.FreeMemory("a"); err != nil {
+		t.Fatalf("FreeMemory: %v", err)
+	}
+	if _, err := r.ReadAt(buf, 0); !errors.Is(err, ErrBlockFreed) {
+		t.Fatalf("ReadAt after free = %v, want ErrBlockFreed", err)
+	}
+}
+
+func TestWriterAtBounds(t *testing.T) {
+	mm := NewMemoryManager(1 << 20)
+	newPatternBlock(t, mm, "a", 100)
+
+	w, err := mm.WriterAt("a")
+	if err != nil {
+		t.Fatalf("WriterAt: %v", err)
+	}
+	if _, err := w.WriteAt(make([]byte, 10), 95); !errors.Is(err, ErrOutOfBounds) {
+		t.Fatalf("WriteAt past the end = %v, want ErrOutOfBounds", err)
+	}
+	if _, err := w.WriteAt([]byte{1}, -1); !errors.Is(err, ErrOutOfBounds) {
+		t.Fatalf("WriteAt(-1) = %v, want ErrOutOfBounds", err)
+	}
+
+	sw := io.NewOffsetWriter(w, 50)
+	if _, err := sw.Write([]byte("hello")); err != nil {
+		t.Fatalf("OffsetWriter.Write: %v", err)
+	}
+	data, err := mm.ReadMemory("a", 50, 5)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("ReadMemory = %q, %v; want hello", data, err)
+	}
+}
+
+func BenchmarkReadAt(b *testing.B) {
+	mm := NewMemoryManager(1 << 24)
+	newPatternBlock(b, mm, "a", 1<<20)
+
+	r, err := mm.ReaderAt("a")
+	if err != nil {
+		b.Fatalf("ReaderAt: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		off := int64(i*len(buf)) % (1<<20 - int64(len(buf)))
+		if _, err := r.ReadAt(buf, off); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -8,22 +8,55 @@ import (
 	"crypto/rand"
 	"crypto/rc4"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"log"
+	"net/http"
 	"os"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	
+	"go-security-scan/ringlog"
+)
+
+var (
+	ErrKeyExists         = errors.New("key already exists")
+	ErrInsecureAlgorithm = errors.New("algorithm is not considered secure")
+	ErrNoMatchingKey     = errors.New("no matching key")
 )
 
+const defaultMaxOperations = 10000
+
 type CryptoManager struct {
+	keyMu sync.RWMutex
 	keyStore map[string][]byte
+	keyAliases map[string]string
 	algorithms map[string]CryptoAlgorithm
-	operations []CryptoOperation
+	opMu sync.Mutex
+	operations *ringlog.Log[CryptoOperation]
+	subMu sync.Mutex
+	subscribers map[<-chan CryptoOperation]chan CryptoOperation
+	metricsMu sync.Mutex
+	metrics   CryptoMetrics
+	
+	StrictMode bool
+	RandSource io.Reader
+	Encoding   Encoding
+	
+	// HashWorkers bounds the goroutines VerifyHashes uses; zero means one
+	// per CPU.
+	HashWorkers int
 }
 
 type CryptoAlgorithm struct {
@@ -43,19 +76,81 @@ type CryptoOperation struct {
 	Details   string    `json:"details"`
 }
 
+// CryptoMetrics counts every logged operation since the manager was created,
+// independent of how many operations the log retains. Algorithms without an
+// entry in the algorithm table are counted in neither the secure nor the
+// insecure total.
+type CryptoMetrics struct {
+	OperationsByType      map[string]int `json:"operations_by_type"`
+	OperationsByAlgorithm map[string]int `json:"operations_by_algorithm"`
+	BytesProcessed        int64          `json:"bytes_processed"`
+	SecureOperations      int            `json:"secure_operations"`
+	InsecureOperations    int            `json:"insecure_operations"`
+}
+
 type EncryptedData struct {
-	Algorithm string `json:"algorithm"`
-	KeyID     string `json:"key_id"`
-	IV        string `json:"iv"`
-	Data      string `json:"data"`
-	Hash      string `json:"hash"`
+	Algorithm string   `json:"algorithm"`
+	KeyID     string   `json:"key_id"`
+	IV        string   `json:"iv"`
+	Data      string   `json:"data"`
+	Hash      string   `json:"hash"`
+	Encoding  Encoding `json:"encoding,omitempty"`
+}
+
+type Encoding string
+
+const (
+	EncodingStd Encoding = "base64"
+	EncodingURL Encoding = "base64url"
+	EncodingHex Encoding = "hex"
+)
+
+func (e Encoding) encode(data []byte) string {
+	switch e {
+	case EncodingURL:
+		return base64.RawURLEncoding.EncodeToString(data)
+	case EncodingHex:
+		return hex.EncodeToString(data)
+	default:
+		return base64.StdEncoding.EncodeToString(data)
+	}
+}
+
+func (e Encoding) decode(text string) ([]byte, error) {
+	switch e {
+	case "", EncodingStd:
+		return base64.StdEncoding.DecodeString(text)
+	case EncodingURL:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(text, "="))
+	case EncodingHex:
+		return hex.DecodeString(text)
+	default:
+		return nil, fmt.Errorf("unknown encoding: %s", e)
+	}
+}
+
+// decodeHash decodes Hash. Blobs written before Encoding existed carry a hex
+// hash alongside base64 IV and data.
+func (ed *EncryptedData) decodeHash() ([]byte, error) {
+	if ed.Encoding == "" {
+		return EncodingHex.decode(ed.Hash)
+	}
+	return ed.Encoding.decode(ed.Hash)
 }
 
 func NewCryptoManager() *CryptoManager {
 	cm := &CryptoManager{
 		keyStore:   make(map[string][]byte),
+		keyAliases: make(map[string]string),
 		algorithms: make(map[string]CryptoAlgorithm),
-		operations: make([]CryptoOperation, 0),
+		operations: ringlog.New[CryptoOperation](defaultMaxOperations),
+		subscribers: make(map[<-chan CryptoOperation]chan CryptoOperation),
+		metrics: CryptoMetrics{
+			OperationsByType:      make(map[string]int),
+			OperationsByAlgorithm: make(map[string]int),
+		},
+		RandSource: rand.Reader,
+		Encoding:   EncodingStd,
 	}
 	
 	cm.initializeAlgorithms()
@@ -112,31 +207,72 @@ func (cm *CryptoManager) initializeAlgorithms() {
 	}
 }
 
-func (cm *CryptoManager) GenerateKey(algorithm string, keyID string) error {
+func (cm *CryptoManager) PreferredAlgorithm() string {
+	preferred := ""
+	for name, algo := range cm.algorithms {
+		if !algo.IsSecure || algo.KeySize == 0 {
+			continue
+		}
+		
+		if preferred == "" {
+			preferred = name
+			continue
+		}
+		
+		current := cm.algorithms[preferred]
+		if algo.KeySize > current.KeySize || (algo.KeySize == current.KeySize && name > preferred) {
+			preferred = name
+		}
+	}
+	return preferred
+}
+
+func (cm *CryptoManager) SupportedSecure() []string {
+	secure := make([]string, 0)
+	for name, algo := range cm.algorithms {
+		if algo.IsSecure {
+			secure = append(secure, name)
+		}
+	}
+	sort.Strings(secure)
+	return secure
+}
+
+func (cm *CryptoManager) GenerateKey(algorithm string, keyID string, overwrite bool) error {
 	algo, exists := cm.algorithms[algorithm]
 	if !exists {
 		return fmt.Errorf("unknown algorithm: %s", algorithm)
 	}
 	
+	if err := cm.checkStrict(algo); err != nil {
+		cm.logOperation("generate_key", algorithm, keyID, 0, "Rejected key generation: insecure algorithm in strict mode")
+		return err
+	}
+	
+	if cm.HasKey(keyID) && !overwrite {
+		cm.logOperation("generate_key", algorithm, keyID, 0, "Rejected key generation: key ID already in use")
+		return fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+	}
+	
 	var key []byte
 	var err error
 	
 	switch algorithm {
 	case "md5", "sha1":
 		key = make([]byte, 16)
-		_, err = rand.Read(key)
+		_, err = io.ReadFull(cm.RandSource, key)
 	case "des":
 		key = make([]byte, 8)
-		_, err = rand.Read(key)
+		_, err = io.ReadFull(cm.RandSource, key)
 	case "rc4":
 		key = make([]byte, 16)
-		_, err = rand.Read(key)
+		_, err = io.ReadFull(cm.RandSource, key)
 	case "aes-128":
 		key = make([]byte, 16)
-		_, err = rand.Read(key)
+		_, err = io.ReadFull(cm.RandSource, key)
 	case "aes-256":
 		key = make([]byte, 32)
-		_, err = rand.Read(key)
+		_, err = io.ReadFull(cm.RandSource, key)
 	default:
 		return fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
@@ -145,27 +281,137 @@ func (cm *CryptoManager) GenerateKey(algorithm string, keyID string) error {
 		return fmt.Errorf("failed to generate key: %v", err)
 	}
 	
+	cm.keyMu.Lock()
+	old, replaced := cm.keyStore[keyID]
+	if replaced && !overwrite {
+		cm.keyMu.Unlock()
+		cm.logOperation("generate_key", algorithm, keyID, 0, "Rejected key generation: key ID already in use")
+		return fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+	}
+	wipeKey(old)
 	cm.keyStore[keyID] = key
+	cm.keyMu.Unlock()
 	
+	if replaced {
+		cm.logOperation("delete_key", "", keyID, len(old), fmt.Sprintf("Zeroed and deleted %d-byte key", len(old)))
+	}
 	cm.logOperation("generate_key", algorithm, keyID, len(key), fmt.Sprintf("Generated %d-byte key for %s", len(key), algorithm))
 	
 	return nil
 }
 
+func (cm *CryptoManager) checkStrict(algo CryptoAlgorithm) error {
+	if cm.StrictMode && !algo.IsSecure {
+		return fmt.Errorf("%w: %s", ErrInsecureAlgorithm, algo.Name)
+	}
+	return nil
+}
+
+func (cm *CryptoManager) HasKey(keyID string) bool {
+	cm.keyMu.RLock()
+	defer cm.keyMu.RUnlock()
+	
+	_, exists := cm.keyStore[keyID]
+	return exists
+}
+
+// AliasKey makes alias resolve to keyID, so data encrypted under a retired
+// key ID can still be decrypted after the key is re-registered under a new ID.
+func (cm *CryptoManager) AliasKey(alias, keyID string) error {
+	cm.keyMu.Lock()
+	if _, exists := cm.keyStore[alias]; exists {
+		cm.keyMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrKeyExists, alias)
+	}
+	if _, exists := cm.keyStore[keyID]; !exists {
+		cm.keyMu.Unlock()
+		return fmt.Errorf("key not found: %s", keyID)
+	}
+	cm.keyAliases[alias] = keyID
+	cm.keyMu.Unlock()
+	
+	cm.logOperation("alias_key", "", keyID, 0, fmt.Sprintf("Aliased %s to %s", alias, keyID))
+	return nil
+}
+
+// lookupKey and resolveKey return a copy of the key so callers can use it
+// after the lock is released without racing a concurrent DeleteKey zeroing
+// it. Callers must wipeKey the copy once they are done with it.
+func (cm *CryptoManager) lookupKey(keyID string) ([]byte, bool) {
+	cm.keyMu.RLock()
+	defer cm.keyMu.RUnlock()
+	
+	key, exists := cm.keyStore[keyID]
+	return append([]byte(nil), key...), exists
+}
+
+func (cm *CryptoManager) resolveKey(keyID string) ([]byte, string, bool) {
+	cm.keyMu.RLock()
+	defer cm.keyMu.RUnlock()
+	
+	if key, exists := cm.keyStore[keyID]; exists {
+		return append([]byte(nil), key...), keyID, true
+	}
+	
+	target, aliased := cm.keyAliases[keyID]
+	if !aliased {
+		return nil, "", false
+	}
+	key, exists := cm.keyStore[target]
+	return append([]byte(nil), key...), target, exists
+}
+
+func wipeKey(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+func (cm *CryptoManager) DeleteKey(keyID string) error {
+	cm.keyMu.Lock()
+	key, exists := cm.keyStore[keyID]
+	if !exists {
+		cm.keyMu.Unlock()
+		return fmt.Errorf("key not found: %s", keyID)
+	}
+	
+	wipeKey(key)
+	delete(cm.keyStore, keyID)
+	for alias, target := range cm.keyAliases {
+		if target == keyID {
+			delete(cm.keyAliases, alias)
+		}
+	}
+	cm.keyMu.Unlock()
+	
+	cm.logOperation("delete_key", "", keyID, len(key), fmt.Sprintf("Zeroed and deleted %d-byte key", len(key)))
+	
+	return nil
+}
+
 func (cm *CryptoManager) EncryptData(algorithm string, keyID string, data []byte) (*EncryptedData, error) {
+	if algorithm == "" {
+		algorithm = cm.PreferredAlgorithm()
+	}
+	
 	algo, exists := cm.algorithms[algorithm]
 	if !exists {
 		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
 	}
 	
-	key, exists := cm.keyStore[keyID]
+	if err := cm.checkStrict(algo); err != nil {
+		cm.logOperation("encrypt", algorithm, keyID, len(data), "Rejected encryption: insecure algorithm in strict mode")
+		return nil, err
+	}
+	
+	key, _, exists := cm.resolveKey(keyID)
 	if !exists {
 		return nil, fmt.Errorf("key not found: %s", keyID)
 	}
+	defer wipeKey(key)
 	
 	var encrypted []byte
 	var iv []byte
-	var err error
 	
 	switch algorithm {
 	case "des":
@@ -193,7 +439,7 @@ func (cm *CryptoManager) EncryptData(algorithm string, keyID string, data []byte
 		}
 		
 		iv = make([]byte, aes.BlockSize)
-		_, err = rand.Read(iv)
+		_, err = io.ReadFull(cm.RandSource, iv)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate IV: %v", err)
 		}
@@ -216,14 +462,18 @@ func (cm *CryptoManager) EncryptData(algorithm string, keyID string, data []byte
 		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
 	
-	hash := cm.calculateHash(data)
+	encoding := cm.Encoding
+	if encoding == "" {
+		encoding = EncodingStd
+	}
 	
 	encryptedData := &EncryptedData{
 		Algorithm: algorithm,
 		KeyID:     keyID,
-		IV:        base64.StdEncoding.EncodeToString(iv),
-		Data:      base64.StdEncoding.EncodeToString(encrypted),
-		Hash:      hash,
+		IV:        encoding.encode(iv),
+		Data:      encoding.encode(encrypted),
+		Hash:      encoding.encode(cm.calculateHash(data)),
+		Encoding:  encoding,
 	}
 	
 	cm.logOperation("encrypt", algorithm, keyID, len(data), fmt.Sprintf("Encrypted %d bytes with %s", len(data), algorithm))
@@ -235,24 +485,90 @@ func (cm *CryptoManager) DecryptData(encryptedData *EncryptedData) ([]byte, erro
 	algorithm := encryptedData.Algorithm
 	keyID := encryptedData.KeyID
 	
-	algo, exists := cm.algorithms[algorithm]
-	if !exists {
+	if _, exists := cm.algorithms[algorithm]; !exists {
 		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
 	}
 	
-	key, exists := cm.keyStore[keyID]
+	key, resolvedID, exists := cm.resolveKey(keyID)
 	if !exists {
 		return nil, fmt.Errorf("key not found: %s", keyID)
 	}
+	defer wipeKey(key)
+	
+	decrypted, err := cm.decryptWithKey(encryptedData, key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted = stripPadding(algorithm, decrypted)
+	
+	details := fmt.Sprintf("Decrypted %d bytes with %s", len(decrypted), algorithm)
+	if resolvedID != keyID {
+		details += fmt.Sprintf(" (key %s via alias %s)", resolvedID, keyID)
+	}
+	cm.logOperation("decrypt", algorithm, resolvedID, len(decrypted), details)
 	
-	encrypted, err := base64.StdEncoding.DecodeString(encryptedData.Data)
+	return decrypted, nil
+}
+
+// TryDecryptAll decrypts with the named key when it resolves, and otherwise
+// tries every key in the store. None of the ciphers here are authenticated,
+// so a candidate key is accepted only when the plaintext matches the stored
+// hash.
+func (cm *CryptoManager) TryDecryptAll(encryptedData *EncryptedData) ([]byte, string, error) {
+	if key, resolvedID, exists := cm.resolveKey(encryptedData.KeyID); exists {
+		wipeKey(key)
+		decrypted, err := cm.DecryptData(encryptedData)
+		return decrypted, resolvedID, err
+	}
+	
+	algorithm := encryptedData.Algorithm
+	if _, exists := cm.algorithms[algorithm]; !exists {
+		return nil, "", fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+	
+	expectedHash, err := encryptedData.decodeHash()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode hash: %v", err)
+	}
+	
+	cm.keyMu.RLock()
+	keys := make(map[string][]byte, len(cm.keyStore))
+	keyIDs := make([]string, 0, len(cm.keyStore))
+	for keyID, key := range cm.keyStore {
+		keys[keyID] = append([]byte(nil), key...)
+		keyIDs = append(keyIDs, keyID)
+	}
+	cm.keyMu.RUnlock()
+	defer func() {
+		for _, key := range keys {
+			wipeKey(key)
+		}
+	}()
+	sort.Strings(keyIDs)
+	
+	for _, keyID := range keyIDs {
+		decrypted, err := cm.decryptWithKey(encryptedData, keys[keyID])
+		if err != nil || subtle.ConstantTimeCompare(cm.calculateHash(decrypted), expectedHash) != 1 {
+			continue
+		}
+		
+		decrypted = stripPadding(algorithm, decrypted)
+		cm.logOperation("decrypt", algorithm, keyID, len(decrypted), fmt.Sprintf("Decrypted %d bytes with %s using fallback key %s (requested %s)", len(decrypted), algorithm, keyID, encryptedData.KeyID))
+		return decrypted, keyID, nil
+	}
+	
+	return nil, "", fmt.Errorf("%w: no stored key decrypts data for %s", ErrNoMatchingKey, encryptedData.KeyID)
+}
+
+func (cm *CryptoManager) decryptWithKey(encryptedData *EncryptedData, key []byte) ([]byte, error) {
+	encrypted, err := encryptedData.Encoding.decode(encryptedData.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode encrypted data: %v", err)
 	}
 	
 	var decrypted []byte
 	
-	switch algorithm {
+	switch encryptedData.Algorithm {
 	case "des":
 		block, err := des.NewCipher(key)
 		if err != nil {
@@ -277,7 +593,7 @@ func (cm *CryptoManager) DecryptData(encryptedData *EncryptedData) ([]byte, erro
 			return nil, fmt.Errorf("failed to create AES cipher: %v", err)
 		}
 		
-		iv, err := base64.StdEncoding.DecodeString(encryptedData.IV)
+		iv, err := encryptedData.Encoding.decode(encryptedData.IV)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode IV: %v", err)
 		}
@@ -286,48 +602,90 @@ func (cm *CryptoManager) DecryptData(encryptedData *EncryptedData) ([]byte, erro
 			return nil, fmt.Errorf("invalid IV size")
 		}
 		
+		if len(encrypted)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("ciphertext is not a multiple of the block size")
+		}
+		
 		decrypted = make([]byte, len(encrypted))
 		mode := cipher.NewCBCDecrypter(block, iv)
 		mode.CryptBlocks(decrypted, encrypted)
 		
-		if len(decrypted) > 0 {
-			padding := int(decrypted[len(decrypted)-1])
-			if padding > 0 && padding <= aes.BlockSize {
-				decrypted = decrypted[:len(decrypted)-padding]
-			}
-		}
-		
 	default:
-		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+		return nil, fmt.Errorf("unsupported algorithm: %s", encryptedData.Algorithm)
 	}
 	
-	cm.logOperation("decrypt", algorithm, keyID, len(decrypted), fmt.Sprintf("Decrypted %d bytes with %s", len(decrypted), algorithm))
-	
 	return decrypted, nil
 }
 
+func stripPadding(algorithm string, decrypted []byte) []byte {
+	if algorithm != "aes-128" && algorithm != "aes-256" || len(decrypted) == 0 {
+		return decrypted
+	}
+	
+	padding := int(decrypted[len(decrypted)-1])
+	if padding > 0 && padding <= aes.BlockSize {
+		decrypted = decrypted[:len(decrypted)-padding]
+	}
+	return decrypted
+}
+
 func (cm *CryptoManager) HashData(algorithm string, data []byte) (string, error) {
-	var hash []byte
-	var err error
+	hasher, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
 	
+	hasher.Write(data)
+	sum := hasher.Sum(nil)
+	
+	cm.logOperation("hash", algorithm, "", len(data), fmt.Sprintf("Hashed %d bytes with %s", len(data), algorithm))
+	
+	return hex.EncodeToString(sum), nil
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
 	switch algorithm {
 	case "md5":
-		hasher := md5.New()
-		hasher.Write(data)
-		hash = hasher.Sum(nil)
-		
+		return md5.New(), nil
 	case "sha1":
-		hasher := sha1.New()
-		hasher.Write(data)
-		hash = hasher.Sum(nil)
-		
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
 	default:
-		return "", fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+}
+
+type Hasher struct {
+	cm        *CryptoManager
+	algorithm string
+	hash      hash.Hash
+	written   int
+}
+
+func (cm *CryptoManager) NewHasher(algorithm string) (*Hasher, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
 	}
 	
-	cm.logOperation("hash", algorithm, "", len(data), fmt.Sprintf("Hashed %d bytes with %s", len(data), algorithm))
+	return &Hasher{cm: cm, algorithm: algorithm, hash: h}, nil
+}
+
+func (h *Hasher) Write(p []byte) (int, error) {
+	n, err := h.hash.Write(p)
+	h.written += n
+	return n, err
+}
+
+func (h *Hasher) Sum() string {
+	sum := hex.EncodeToString(h.hash.Sum(nil))
 	
-	return hex.EncodeToString(hash), nil
+	h.cm.logOperation("hash", h.algorithm, "", h.written, fmt.Sprintf("Hashed %d bytes with %s (streamed)", h.written, h.algorithm))
+	
+	return sum
 }
 
 func (cm *CryptoManager) VerifyHash(algorithm string, data []byte, expectedHash string) (bool, error) {
@@ -339,10 +697,76 @@ func (cm *CryptoManager) VerifyHash(algorithm string, data []byte, expectedHash
 	return actualHash == expectedHash, nil
 }
 
-func (cm *CryptoManager) calculateHash(data []byte) string {
+// VerifyHashes hashes every item and compares it in constant time against
+// the hex digest with the same key in expected. Keys present in only one of
+// the maps are reported as false.
+func (cm *CryptoManager) VerifyHashes(algorithm string, items map[string][]byte, expected map[string]string) (map[string]bool, error) {
+	if _, err := newHash(algorithm); err != nil {
+		return nil, err
+	}
+	
+	results := make(map[string]bool, len(items))
+	for key := range expected {
+		results[key] = false
+	}
+	
+	keys := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	
+	workers := cm.HashWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+	
+	total := 0
+	for _, data := range items {
+		total += len(data)
+	}
+	
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				h, _ := newHash(algorithm)
+				h.Write(items[key])
+				actual := hex.EncodeToString(h.Sum(nil))
+				
+				want, ok := expected[key]
+				match := ok && subtle.ConstantTimeCompare([]byte(actual), []byte(strings.ToLower(want))) == 1
+				
+				mu.Lock()
+				results[key] = match
+				mu.Unlock()
+			}
+		}()
+	}
+	
+	for key := range items {
+		keys <- key
+	}
+	close(keys)
+	wg.Wait()
+	
+	matched := 0
+	for _, ok := range results {
+		if ok {
+			matched++
+		}
+	}
+	cm.logOperation("hash", algorithm, "", total, fmt.Sprintf("Verified %d items with %s, %d matched", len(results), algorithm, matched))
+	
+	return results, nil
+}
+
+func (cm *CryptoManager) calculateHash(data []byte) []byte {
 	hasher := md5.New()
 	hasher.Write(data)
-	return hex.EncodeToString(hasher.Sum(nil))
+	return hasher.Sum(nil)
 }
 
 func (cm *CryptoManager) GenerateWeakPassword() string {
@@ -385,10 +809,11 @@ func (cm *CryptoManager) DecryptPassword(encryptedPassword string) (string, erro
 }
 
 func (cm *CryptoManager) CreateDigitalSignature(data []byte, keyID string) (string, error) {
-	key, exists := cm.keyStore[keyID]
+	key, exists := cm.lookupKey(keyID)
 	if !exists {
 		return "", fmt.Errorf("key not found: %s", keyID)
 	}
+	defer wipeKey(key)
 	
 	hash, err := cm.HashData("md5", data)
 	if err != nil {
@@ -404,10 +829,11 @@ func (cm *CryptoManager) CreateDigitalSignature(data []byte, keyID string) (stri
 }
 
 func (cm *CryptoManager) VerifyDigitalSignature(data []byte, signature string, keyID string) (bool, error) {
-	key, exists := cm.keyStore[keyID]
+	key, exists := cm.lookupKey(keyID)
 	if !exists {
 		return false, fmt.Errorf("key not found: %s", keyID)
 	}
+	defer wipeKey(key)
 	
 	sigBytes, err := base64.StdEncoding.DecodeString(signature)
 	if err != nil {
@@ -437,30 +863,200 @@ func (cm *CryptoManager) logOperation(opType, algorithm, keyID string, dataSize
 		Details:   details,
 	}
 	
-	cm.operations = append(cm.operations, operation)
+	cm.opMu.Lock()
+	cm.operations.Append(operation)
+	cm.opMu.Unlock()
+	cm.recordMetrics(operation)
+	cm.publish(operation)
 	
 	fmt.Printf("[%s] %s: %s with %s (size=%d) - %s\n",
 		operation.Timestamp.Format("2006-01-02 15:04:05"),
 		operation.Type, operation.Algorithm, operation.KeyID, operation.DataSize, operation.Details)
 }
 
+func (cm *CryptoManager) recordMetrics(operation CryptoOperation) {
+	cm.metricsMu.Lock()
+	defer cm.metricsMu.Unlock()
+	
+	cm.metrics.OperationsByType[operation.Type]++
+	switch operation.Type {
+	case "encrypt", "decrypt", "hash":
+		cm.metrics.BytesProcessed += int64(operation.DataSize)
+	}
+	
+	if operation.Algorithm == "" {
+		return
+	}
+	cm.metrics.OperationsByAlgorithm[operation.Algorithm]++
+	
+	if algo, ok := cm.algorithms[operation.Algorithm]; ok {
+		if algo.IsSecure {
+			cm.metrics.SecureOperations++
+		} else {
+			cm.metrics.InsecureOperations++
+		}
+	}
+}
+
+func (cm *CryptoManager) Metrics() CryptoMetrics {
+	cm.metricsMu.Lock()
+	defer cm.metricsMu.Unlock()
+	
+	metrics := cm.metrics
+	metrics.OperationsByType = make(map[string]int, len(cm.metrics.OperationsByType))
+	for opType, count := range cm.metrics.OperationsByType {
+		metrics.OperationsByType[opType] = count
+	}
+	metrics.OperationsByAlgorithm = make(map[string]int, len(cm.metrics.OperationsByAlgorithm))
+	for algorithm, count := range cm.metrics.OperationsByAlgorithm {
+		metrics.OperationsByAlgorithm[algorithm] = count
+	}
+	return metrics
+}
+
+func (cm *CryptoManager) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cm.Metrics())
+}
+
+func (cm *CryptoManager) PrometheusHandler(w http.ResponseWriter, r *http.Request) {
+	metrics := cm.Metrics()
+	
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	
+	fmt.Fprintln(w, "# HELP crypto_operations_total Crypto operations by type.")
+	fmt.Fprintln(w, "# TYPE crypto_operations_total counter")
+	for _, opType := range sortedKeys(metrics.OperationsByType) {
+		fmt.Fprintf(w, "crypto_operations_total{type=%q} %d\n", opType, metrics.OperationsByType[opType])
+	}
+	
+	fmt.Fprintln(w, "# HELP crypto_algorithm_operations_total Crypto operations by algorithm.")
+	fmt.Fprintln(w, "# TYPE crypto_algorithm_operations_total counter")
+	for _, algorithm := range sortedKeys(metrics.OperationsByAlgorithm) {
+		secure := "unknown"
+		if algo, ok := cm.algorithms[algorithm]; ok {
+			secure = strconv.FormatBool(algo.IsSecure)
+		}
+		fmt.Fprintf(w, "crypto_algorithm_operations_total{algorithm=%q,secure=%q} %d\n", algorithm, secure, metrics.OperationsByAlgorithm[algorithm])
+	}
+	
+	fmt.Fprintln(w, "# HELP crypto_bytes_processed_total Bytes encrypted, decrypted or hashed.")
+	fmt.Fprintln(w, "# TYPE crypto_bytes_processed_total counter")
+	fmt.Fprintf(w, "crypto_bytes_processed_total %d\n", metrics.BytesProcessed)
+	
+	fmt.Fprintln(w, "# HELP crypto_secure_operations_total Operations using an algorithm rated secure.")
+	fmt.Fprintln(w, "# TYPE crypto_secure_operations_total counter")
+	fmt.Fprintf(w, "crypto_secure_operations_total %d\n", metrics.SecureOperations)
+	
+	fmt.Fprintln(w, "# HELP crypto_insecure_operations_total Operations using an algorithm rated insecure.")
+	fmt.Fprintln(w, "# TYPE crypto_insecure_operations_total counter")
+	fmt.Fprintf(w, "crypto_insecure_operations_total %d\n", metrics.InsecureOperations)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (cm *CryptoManager) Subscribe() <-chan CryptoOperation {
+	ch := make(chan CryptoOperation, 64)
+	
+	cm.subMu.Lock()
+	cm.subscribers[ch] = ch
+	cm.subMu.Unlock()
+	
+	return ch
+}
+
+func (cm *CryptoManager) Unsubscribe(sub <-chan CryptoOperation) {
+	cm.subMu.Lock()
+	defer cm.subMu.Unlock()
+	
+	if ch, ok := cm.subscribers[sub]; ok {
+		delete(cm.subscribers, sub)
+		close(ch)
+	}
+}
+
+func (cm *CryptoManager) publish(operation CryptoOperation) {
+	cm.subMu.Lock()
+	defer cm.subMu.Unlock()
+	
+	for _, ch := range cm.subscribers {
+		select {
+		case ch <- operation:
+		default:
+		}
+	}
+}
+
+func (cm *CryptoManager) OperationsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	
+	sub := cm.Subscribe()
+	defer cm.Unsubscribe(sub)
+	
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case operation, ok := <-sub:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(operation)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", operation.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
 func (cm *CryptoManager) GetAlgorithms() map[string]CryptoAlgorithm {
 	return cm.algorithms
 }
 
 func (cm *CryptoManager) GetOperations() []CryptoOperation {
-	return cm.operations
+	cm.opMu.Lock()
+	defer cm.opMu.Unlock()
+	return cm.operations.Entries()
+}
+
+func (cm *CryptoManager) OperationCount() int {
+	cm.opMu.Lock()
+	defer cm.opMu.Unlock()
+	return cm.operations.Len()
+}
+
+func (cm *CryptoManager) SetMaxOperations(max int) {
+	cm.opMu.Lock()
+	defer cm.opMu.Unlock()
+	cm.operations.SetMax(max)
 }
 
 func (cm *CryptoManager) ExportOperations() ([]byte, error) {
-	return json.MarshalIndent(cm.operations, "", "  ")
+	return json.MarshalIndent(cm.GetOperations(), "", "  ")
 }
 
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: go run main.go <command> [args...]")
 		fmt.Println("Commands:")
-		fmt.Println("  generate_key <algorithm> <key_id> - Generate encryption key")
+		fmt.Println("  generate_key <algorithm> <key_id> [overwrite] - Generate encryption key")
 		fmt.Println("  encrypt <algorithm> <key_id> <data> - Encrypt data")
 		fmt.Println("  decrypt <encrypted_json> - Decrypt data")
 		fmt.Println("  hash <algorithm> <data> - Hash data")
@@ -471,6 +1067,7 @@ func main() {
 		fmt.Println("  sign <key_id> <data> - Create digital signature")
 		fmt.Println("  verify_signature <key_id> <data> <signature> - Verify signature")
 		fmt.Println("  algorithms - List available algorithms")
+		fmt.Println("  preferred - Show preferred secure algorithm")
 		fmt.Println("  operations - Show operations")
 		fmt.Println("  export - Export operations")
 		return
@@ -483,14 +1080,15 @@ func main() {
 	switch command {
 	case "generate_key":
 		if len(os.Args) < 4 {
-			fmt.Println("Usage: generate_key <algorithm> <key_id>")
+			fmt.Println("Usage: generate_key <algorithm> <key_id> [overwrite]")
 			return
 		}
 		
 		algorithm := os.Args[2]
 		keyID := os.Args[3]
+		overwrite := len(os.Args) > 4 && os.Args[4] == "overwrite"
 		
-		err := cm.GenerateKey(algorithm, keyID)
+		err := cm.GenerateKey(algorithm, keyID, overwrite)
 		if err != nil {
 			fmt.Printf("Error generating key: %v\n", err)
 		} else {
@@ -635,6 +1233,10 @@ func main() {
 			fmt.Printf("%s: %s (secure: %v)\n", name, algo.Description, algo.IsSecure)
 		}
 		
+	case "preferred":
+		fmt.Printf("Preferred algorithm: %s\n", cm.PreferredAlgorithm())
+		fmt.Printf("Secure algorithms: %s\n", strings.Join(cm.SupportedSecure(), ", "))
+	
 	case "operations":
 		operations := cm.GetOperations()
 		fmt.Printf("Total operations: %d\n", len(operations))
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+	"testing"
+)
+
+func TestDeleteKeyZeroesStoredKey(t *testing.T) {
+	cm := NewCryptoManager()
+	if err := cm.GenerateKey("aes-256", "k", false); err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cm.keyMu.RLock()
+	stored := cm.keyStore["k"]
+	cm.keyMu.RUnlock()
+
+	if err := cm.DeleteKey("k"); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+	if !bytes.Equal(stored, make([]byte, len(stored))) {
+		t.Fatalf("DeleteKey left key material behind: %x", stored)
+	}
+}
+
+func TestKeyUseRacesDeleteKey(t *testing.T) {
+	cm := NewCryptoManager()
+	cm.SetMaxOperations(100)
+	if err := cm.GenerateKey("aes-256", "k", false); err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	plaintext := []byte("sixteen byte msg")
+	encrypted, err := cm.EncryptData("aes-256", "k", plaintext)
+	if err != nil {
+		t.Fatalf("EncryptData: %v", err)
+	}
+
+	const iterations = 200
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				fn()
+			}
+		}()
+	}
+
+	run(func() { cm.EncryptData("aes-256", "k", plaintext) })
+	run(func() { cm.DecryptData(encrypted) })
+	run(func() { cm.TryDecryptAll(encrypted) })
+	run(func() {
+		if signature, err := cm.CreateDigitalSignature(plaintext, "k"); err == nil {
+			cm.VerifyDigitalSignature(plaintext, signature, "k")
+		}
+	})
+	run(func() {
+		cm.DeleteKey("k")
+		cm.GenerateKey("aes-256", "k", true)
+	})
+	wg.Wait()
+}
+
+func TestTryDecryptAllReadsLegacyHexHash(t *testing.T) {
+	cm := NewCryptoManager()
+	if err := cm.GenerateKey("aes-256", "k", false); err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	plaintext := []byte("sixteen byte msg")
+	encrypted, err := cm.EncryptData("aes-256", "k", plaintext)
+	if err != nil {
+		t.Fatalf("EncryptData: %v", err)
+	}
+
+	hash, err := encrypted.Encoding.decode(encrypted.Hash)
+	if err != nil {
+		t.Fatalf("decode hash: %v", err)
+	}
+	legacy := *encrypted
+	legacy.KeyID = "retired"
+	legacy.Hash = hex.EncodeToString(hash)
+	legacy.Encoding = ""
+
+	decrypted, keyID, err := cm.TryDecryptAll(&legacy)
+	if err != nil {
+		t.Fatalf("TryDecryptAll: %v", err)
+	}
+	if keyID != "k" || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("TryDecryptAll = %q via %q, want %q via k", decrypted, keyID, plaintext)
+	}
+}
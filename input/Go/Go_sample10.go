@@ -1,16 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -26,6 +36,7 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	IsActive  bool      `json:"is_active"`
+	Version   int       `json:"version"`
 }
 
 type CreateUserRequest struct {
@@ -41,8 +52,36 @@ type UpdateUserRequest struct {
 	FirstName *string `json:"first_name,omitempty"`
 	LastName  *string `json:"last_name,omitempty"`
 	IsActive  *bool   `json:"is_active,omitempty"`
+	Version   *int    `json:"version,omitempty"`
 }
 
+func (r *UpdateUserRequest) fields() []string {
+	var fields []string
+	if r.Username != nil {
+		fields = append(fields, "username")
+	}
+	if r.Email != nil {
+		fields = append(fields, "email")
+	}
+	if r.FirstName != nil {
+		fields = append(fields, "first_name")
+	}
+	if r.LastName != nil {
+		fields = append(fields, "last_name")
+	}
+	if r.IsActive != nil {
+		fields = append(fields, "is_active")
+	}
+	return fields
+}
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrVersionConflict = errors.New("user was modified by another request")
+)
+
+const maxUpdateRetries = 3
+
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
@@ -62,12 +101,14 @@ type UserStore struct {
 	mu    sync.RWMutex
 	users map[int]*User
 	nextID int
+	fieldVersions map[int]map[string]int
 }
 
 func NewUserStore() *UserStore {
 	store := &UserStore{
 		users:  make(map[int]*User),
 		nextID: 1,
+		fieldVersions: make(map[int]map[string]int),
 	}
 	store.seedData()
 	return store
@@ -94,6 +135,7 @@ func (s *UserStore) CreateUser(user *User) *User {
 	s.nextID++
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	user.Version = 1
 	
 	s.users[user.ID] = user
 	return user
@@ -118,13 +160,17 @@ func (s *UserStore) GetAllUsers() []*User {
 	return users
 }
 
-func (s *UserStore) UpdateUser(id int, updates *UpdateUserRequest) (*User, bool) {
+func (s *UserStore) UpdateUser(id int, updates *UpdateUserRequest) (*User, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
 	user, exists := s.users[id]
 	if !exists {
-		return nil, false
+		return nil, ErrUserNotFound
+	}
+	
+	if updates.Version != nil && *updates.Version != user.Version {
+		return nil, ErrVersionConflict
 	}
 
 	if updates.Username != nil {
@@ -143,8 +189,51 @@ func (s *UserStore) UpdateUser(id int, updates *UpdateUserRequest) (*User, bool)
 		user.IsActive = *updates.IsActive
 	}
 	user.UpdatedAt = time.Now()
+	user.Version++
+	
+	if s.fieldVersions[id] == nil {
+		s.fieldVersions[id] = make(map[string]int)
+	}
+	for _, field := range updates.fields() {
+		s.fieldVersions[id][field] = user.Version
+	}
+	
+	return user, nil
+}
+
+func (s *UserStore) UpdateUserWithRetry(id int, updates *UpdateUserRequest, maxRetries int) (*User, error) {
+	for attempt := 0; ; attempt++ {
+		user, err := s.UpdateUser(id, updates)
+		if err != ErrVersionConflict || attempt >= maxRetries {
+			return user, err
+		}
+		
+		currentVersion, err := s.rebase(id, *updates.Version, updates.fields())
+		if err != nil {
+			return nil, err
+		}
+		
+		retry := *updates
+		retry.Version = &currentVersion
+		updates = &retry
+	}
+}
+
+func (s *UserStore) rebase(id, baseVersion int, fields []string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	
-	return user, true
+	user, exists := s.users[id]
+	if !exists {
+		return 0, ErrUserNotFound
+	}
+	
+	for _, field := range fields {
+		if s.fieldVersions[id][field] > baseVersion {
+			return 0, ErrVersionConflict
+		}
+	}
+	return user.Version, nil
 }
 
 func (s *UserStore) DeleteUser(id int) bool {
@@ -154,6 +243,7 @@ func (s *UserStore) DeleteUser(id int) bool {
 	_, exists := s.users[id]
 	if exists {
 		delete(s.users, id)
+		delete(s.fieldVersions, id)
 	}
 	return exists
 }
@@ -205,25 +295,43 @@ func (s *UserStore) GetUsersPaginated(page, pageSize int) (*PaginatedResponse, e
 	}, nil
 }
 
+const gzipMinSize = 1024
+
+const usersCacheMaxEntries = 256
+
 type APIServer struct {
-	store  *UserStore
-	router *mux.Router
+	store        *UserStore
+	router       *mux.Router
+	cacheMu      sync.RWMutex
+	usersCache   map[string][]byte
+	cacheGen     uint64
+	adminToken   string
+	inFlight     int64
+	shuttingDown int32
+	shutdownCh   chan struct{}
 }
 
 func NewAPIServer() *APIServer {
 	server := &APIServer{
-		store:  NewUserStore(),
-		router: mux.NewRouter(),
+		store:      NewUserStore(),
+		router:     mux.NewRouter(),
+		usersCache: make(map[string][]byte),
+		shutdownCh: make(chan struct{}),
 	}
 	server.setupRoutes()
 	return server
 }
 
 func (s *APIServer) setupRoutes() {
+	s.router.Use(s.requestIDMiddleware)
+	s.router.Use(s.recoveryMiddleware)
+	s.router.Use(s.inFlightMiddleware)
+	
 	api := s.router.PathPrefix("/api").Subrouter()
 	api.Use(s.loggingMiddleware)
 	api.Use(s.corsMiddleware)
 	api.Use(s.jsonMiddleware)
+	api.Use(s.gzipMiddleware)
 
 	api.HandleFunc("/users", s.getUsers).Methods("GET")
 	api.HandleFunc("/users", s.createUser).Methods("POST")
@@ -234,6 +342,33 @@ func (s *APIServer) setupRoutes() {
 	s.router.HandleFunc("/health", s.healthCheck).Methods("GET")
 	
 	s.router.HandleFunc("/metrics", s.getMetrics).Methods("GET")
+	
+	admin := s.router.PathPrefix("/admin").Subrouter()
+	admin.Use(s.loggingMiddleware)
+	admin.Use(s.jsonMiddleware)
+	admin.Use(s.adminAuthMiddleware)
+	
+	admin.HandleFunc("/shutdown", s.shutdown).Methods("POST")
+}
+
+func (s *APIServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+func (s *APIServer) InFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+func (s *APIServer) RequestShutdown() bool {
+	if !atomic.CompareAndSwapInt32(&s.shuttingDown, 0, 1) {
+		return false
+	}
+	close(s.shutdownCh)
+	return true
+}
+
+func (s *APIServer) ShutdownRequested() <-chan struct{} {
+	return s.shutdownCh
 }
 
 func (s *APIServer) loggingMiddleware(next http.Handler) http.Handler {
@@ -249,6 +384,77 @@ func (s *APIServer) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+func (s *APIServer) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func requestIDFrom(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDKey).(string)
+	return requestID
+}
+
+func (s *APIServer) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("panic serving %s %s (request_id=%s): %v\n%s", r.Method, r.URL.Path, requestIDFrom(r), rec, debug.Stack())
+				
+				w.Header().Set("Content-Type", "application/json")
+				s.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) inFlightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			s.writeErrorResponse(w, http.StatusForbidden, "Admin endpoints are disabled")
+			return
+		}
+		
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+		
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *APIServer) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -271,6 +477,48 @@ func (s *APIServer) jsonMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+func (s *APIServer) gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		
+		buffered := &bufferedResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+		
+		w.Header().Add("Vary", "Accept-Encoding")
+		
+		if buffered.body.Len() < gzipMinSize {
+			w.WriteHeader(buffered.statusCode)
+			w.Write(buffered.body.Bytes())
+			return
+		}
+		
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(buffered.statusCode)
+		
+		gz := gzip.NewWriter(w)
+		gz.Write(buffered.body.Bytes())
+		gz.Close()
+	})
+}
+
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (bw *bufferedResponseWriter) WriteHeader(code int) {
+	bw.statusCode = code
+}
+
+func (bw *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return bw.body.Write(data)
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -282,33 +530,75 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 func (s *APIServer) getUsers(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("page_size")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	
+	paginated := page != 0 || pageSize != 0
+	cacheKey := "all"
+	if paginated {
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 10
+		}
+		cacheKey = fmt.Sprintf("page=%d&page_size=%d", page, pageSize)
+	}
 	
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
+	s.cacheMu.RLock()
+	cached, ok := s.usersCache[cacheKey]
+	gen := s.cacheGen
+	s.cacheMu.RUnlock()
 	
-	if page == 0 && pageSize == 0 {
-		users := s.store.GetAllUsers()
-		response := APIResponse{
+	if ok {
+		w.Write(cached)
+		return
+	}
+	
+	var response APIResponse
+	if !paginated {
+		response = APIResponse{
 			Success: true,
-			Data:    users,
+			Data:    s.store.GetAllUsers(),
+		}
+	} else {
+		paginatedUsers, err := s.store.GetUsersPaginated(page, pageSize)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		
+		response = APIResponse{
+			Success: true,
+			Data:    paginatedUsers,
 		}
-		json.NewEncoder(w).Encode(response)
-		return
 	}
 	
-	paginatedUsers, err := s.store.GetUsersPaginated(page, pageSize)
-	if err != nil {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(response); err != nil {
 		s.writeErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	
-	response := APIResponse{
-		Success: true,
-		Data:    paginatedUsers,
+	s.cacheMu.Lock()
+	// Skip the fill if a write invalidated the cache while this response
+	// was being built, otherwise the stale page would be cached again.
+	if s.cacheGen == gen {
+		if len(s.usersCache) >= usersCacheMaxEntries {
+			s.usersCache = make(map[string][]byte)
+		}
+		s.usersCache[cacheKey] = body.Bytes()
 	}
-	json.NewEncoder(w).Encode(response)
+	s.cacheMu.Unlock()
+	
+	w.Write(body.Bytes())
+}
+
+func (s *APIServer) invalidateUsersCache() {
+	s.cacheMu.Lock()
+	s.usersCache = make(map[string][]byte)
+	s.cacheGen++
+	s.cacheMu.Unlock()
 }
 
 func (s *APIServer) getUser(w http.ResponseWriter, r *http.Request) {
@@ -357,6 +647,7 @@ func (s *APIServer) createUser(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	createdUser := s.store.CreateUser(user)
+	s.invalidateUsersCache()
 	
 	w.WriteHeader(http.StatusCreated)
 	response := APIResponse{
@@ -381,11 +672,16 @@ func (s *APIServer) updateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	updatedUser, exists := s.store.UpdateUser(id, &req)
-	if !exists {
+	updatedUser, err := s.store.UpdateUserWithRetry(id, &req, maxUpdateRetries)
+	if err == ErrUserNotFound {
 		s.writeErrorResponse(w, http.StatusNotFound, "User not found")
 		return
 	}
+	if err == ErrVersionConflict {
+		s.writeErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.invalidateUsersCache()
 	
 	response := APIResponse{
 		Success: true,
@@ -408,6 +704,7 @@ func (s *APIServer) deleteUser(w http.ResponseWriter, r *http.Request) {
 		s.writeErrorResponse(w, http.StatusNotFound, "User not found")
 		return
 	}
+	s.invalidateUsersCache()
 	
 	response := APIResponse{
 		Success: true,
@@ -454,6 +751,28 @@ func (s *APIServer) getMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (s *APIServer) shutdown(w http.ResponseWriter, r *http.Request) {
+	// The shutdown request itself is still counted as in flight.
+	inFlight := s.InFlight() - 1
+	
+	if !s.RequestShutdown() {
+		s.writeErrorResponse(w, http.StatusConflict, "Shutdown already in progress")
+		return
+	}
+	
+	log.Printf("Shutdown requested via admin endpoint (request_id=%s)", requestIDFrom(r))
+	
+	w.WriteHeader(http.StatusAccepted)
+	response := APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"in_flight": inFlight,
+		},
+		Message: "Shutdown initiated",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (s *APIServer) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.WriteHeader(statusCode)
 	response := APIResponse{
@@ -469,22 +788,50 @@ func (s *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 var startTime = time.Now()
 
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func main() {
 	fmt.Println("Go Web Server with REST API")
 	fmt.Println("===========================")
 	
+	addr := flag.String("addr", envOrDefault("ADDR", ":8080"), "listen address")
+	certFile := flag.String("tls-cert", os.Getenv("TLS_CERT_FILE"), "TLS certificate file")
+	keyFile := flag.String("tls-key", os.Getenv("TLS_KEY_FILE"), "TLS private key file")
+	flag.Parse()
+	
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("-tls-cert and -tls-key must be set together")
+	}
+	useTLS := *certFile != ""
+	
 	server := NewAPIServer()
+	server.SetAdminToken(os.Getenv("ADMIN_TOKEN"))
 	
 	httpServer := &http.Server{
-		Addr:         ":8080",
+		Addr:         *addr,
 		Handler:      server,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 	
+	if useTLS {
+		httpServer.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	
 	go func() {
-		log.Printf("Starting server on http://localhost:8080")
+		scheme := "http"
+		if useTLS {
+			scheme = "https"
+		}
+		log.Printf("Starting server on %s://%s", scheme, *addr)
 		log.Printf("API endpoints:")
 		log.Printf("  GET    /health - Health check")
 		log.Printf("  GET    /metrics - Server metrics")
@@ -493,17 +840,28 @@ func main() {
 		log.Printf("  GET    /api/users/{id} - Get user by ID")
 		log.Printf("  PUT    /api/users/{id} - Update user")
 		log.Printf("  DELETE /api/users/{id} - Delete user")
+		log.Printf("  POST   /admin/shutdown - Graceful shutdown (requires ADMIN_TOKEN)")
 		
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if useTLS {
+			err = httpServer.ListenAndServeTLS(*certFile, *keyFile)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 	
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+		server.RequestShutdown()
+	case <-server.ShutdownRequested():
+	}
 	
-	log.Println("Shutting down server...")
+	log.Printf("Shutting down server (%d requests in flight)...", server.InFlight())
 	
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,182 @@
+package loginguard
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+const defaultMaxEntries = 100000
+
+type entry struct {
+	attempts    []time.Time
+	lockouts    int
+	lockedUntil time.Time
+}
+
+type Limiter struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	lockout     time.Duration
+	maxLockout  time.Duration
+	maxEntries  int
+	entries     map[string]*entry
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+func New(maxAttempts int, window, lockout, maxLockout time.Duration) *Limiter {
+	return &Limiter{
+		maxAttempts: maxAttempts,
+		window:      window,
+		lockout:     lockout,
+		maxLockout:  maxLockout,
+		maxEntries:  defaultMaxEntries,
+		entries:     make(map[string]*entry),
+		now:         time.Now,
+	}
+}
+
+// SetMaxEntries caps how many keys are tracked. Once full, unlocked keys are
+// evicted to make room; keys that are currently locked out are kept.
+func (l *Limiter) SetMaxEntries(max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxEntries = max
+}
+
+// Allow reserves an attempt for every key, counting it as a failure until
+// Reset is called. Concurrent callers therefore cannot all get past the
+// limit before their failures are recorded.
+func (l *Limiter) Allow(keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.maybeSweep(now)
+
+	for _, key := range keys {
+		e, ok := l.entries[key]
+		if !ok {
+			continue
+		}
+		if now.Before(e.lockedUntil) {
+			return fmt.Errorf("%w: retry in %v", ErrTooManyAttempts, e.lockedUntil.Sub(now).Round(time.Second))
+		}
+		e.attempts = pruneBefore(e.attempts, now.Add(-l.window))
+		if len(e.attempts) >= l.maxAttempts {
+			return fmt.Errorf("%w: too many attempts in progress", ErrTooManyAttempts)
+		}
+	}
+
+	for _, key := range keys {
+		e := l.entry(key, now)
+		e.attempts = append(e.attempts, now)
+	}
+	return nil
+}
+
+// Fail records that an attempt failed and locks out keys that have reached
+// the limit. An attempt not reserved through Allow is counted here instead.
+func (l *Limiter) Fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.maybeSweep(now)
+
+	for _, key := range keys {
+		e := l.entry(key, now)
+
+		e.attempts = pruneBefore(e.attempts, now.Add(-l.window))
+		if len(e.attempts) == 0 {
+			e.attempts = append(e.attempts, now)
+		}
+		if len(e.attempts) < l.maxAttempts {
+			continue
+		}
+
+		e.lockedUntil = now.Add(l.lockoutFor(e.lockouts))
+		e.lockouts++
+		e.attempts = nil
+	}
+}
+
+func (l *Limiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		delete(l.entries, key)
+	}
+}
+
+func (l *Limiter) entry(key string, now time.Time) *entry {
+	if e, ok := l.entries[key]; ok {
+		return e
+	}
+
+	if l.maxEntries > 0 && len(l.entries) >= l.maxEntries {
+		l.sweep(now)
+		for k, e := range l.entries {
+			if len(l.entries) < l.maxEntries {
+				break
+			}
+			if !now.Before(e.lockedUntil) {
+				delete(l.entries, k)
+			}
+		}
+	}
+
+	e := &entry{}
+	l.entries[key] = e
+	return e
+}
+
+func (l *Limiter) maybeSweep(now time.Time) {
+	if now.Sub(l.lastSweep) >= l.window {
+		l.sweep(now)
+	}
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if l.idle(e, now) {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *Limiter) lockoutFor(lockouts int) time.Duration {
+	d := l.lockout
+	for i := 0; i < lockouts && d < l.maxLockout; i++ {
+		d *= 2
+	}
+	if d > l.maxLockout {
+		d = l.maxLockout
+	}
+	return d
+}
+
+// idle reports whether an entry has no recent attempts and has been
+// unlocked for long enough that its lockout history can be forgotten.
+func (l *Limiter) idle(e *entry, now time.Time) bool {
+	if len(pruneBefore(e.attempts, now.Add(-l.window))) > 0 {
+		return false
+	}
+	return now.Sub(e.lockedUntil) > l.maxLockout
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
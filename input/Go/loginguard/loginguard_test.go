@@ -0,0 +1,185 @@
+package loginguard
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+func newTestLimiter() (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(3, time.Minute, time.Minute, 4*time.Minute)
+	l.now = clock.Now
+	return l, clock
+}
+
+func attempt(l *Limiter, ok bool, keys ...string) error {
+	if err := l.Allow(keys...); err != nil {
+		return err
+	}
+	if ok {
+		l.Reset(keys...)
+	} else {
+		l.Fail(keys...)
+	}
+	return nil
+}
+
+func TestLockoutAfterMaxAttempts(t *testing.T) {
+	l, clock := newTestLimiter()
+
+	for i := 0; i < 3; i++ {
+		if err := attempt(l, false, "alice"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	err := l.Allow("alice")
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Allow after 3 failures = %v, want ErrTooManyAttempts", err)
+	}
+	if err := l.Allow("bob"); err != nil {
+		t.Fatalf("Allow(bob) = %v, other keys must not be affected", err)
+	}
+
+	clock.Advance(time.Minute)
+	if err := l.Allow("alice"); err != nil {
+		t.Fatalf("Allow after lockout expired = %v", err)
+	}
+}
+
+func TestLockoutBacksOffExponentially(t *testing.T) {
+	l, clock := newTestLimiter()
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		for i := 0; i < 3; i++ {
+			if err := attempt(l, false, "alice"); err != nil {
+				t.Fatalf("attempt %d: %v", i+1, err)
+			}
+		}
+
+		clock.Advance(want - time.Second)
+		if err := l.Allow("alice"); !errors.Is(err, ErrTooManyAttempts) {
+			t.Fatalf("Allow before %v lockout expired = %v", want, err)
+		}
+		clock.Advance(time.Second)
+	}
+}
+
+func TestResetClearsFailures(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	for i := 0; i < 2; i++ {
+		if err := attempt(l, false, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := attempt(l, true, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := attempt(l, false, "alice"); err != nil {
+			t.Fatalf("attempt %d after reset: %v", i+1, err)
+		}
+	}
+}
+
+func TestAllowRejectsAllKeysTogether(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	for i := 0; i < 3; i++ {
+		if err := attempt(l, false, "ip:10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Allow("user:alice", "ip:10.0.0.1"); err == nil {
+		t.Fatal("expected Allow to fail when any key is locked")
+	}
+	if _, ok := l.entries["user:alice"]; ok {
+		t.Fatal("a rejected Allow must not reserve attempts on the other keys")
+	}
+}
+
+func TestConcurrentAllowReservesAttempts(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	var allowed int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if l.Allow("alice") == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Fatalf("%d concurrent attempts were allowed, want 3", allowed)
+	}
+}
+
+func TestIdleEntriesAreEvicted(t *testing.T) {
+	l, clock := newTestLimiter()
+
+	for i := 0; i < 1000; i++ {
+		l.Fail(fmt.Sprintf("user:%d", i))
+	}
+	if len(l.entries) != 1000 {
+		t.Fatalf("tracking %d entries, want 1000", len(l.entries))
+	}
+
+	clock.Advance(5 * time.Minute)
+	l.Fail("user:new")
+	if len(l.entries) != 1 {
+		t.Fatalf("tracking %d entries after they went idle, want 1", len(l.entries))
+	}
+}
+
+func TestMaxEntriesKeepsLockedKeys(t *testing.T) {
+	l, _ := newTestLimiter()
+	l.SetMaxEntries(10)
+
+	for i := 0; i < 3; i++ {
+		if err := attempt(l, false, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		l.Fail(fmt.Sprintf("user:%d", i))
+	}
+
+	if len(l.entries) > 10 {
+		t.Fatalf("tracking %d entries, want at most 10", len(l.entries))
+	}
+	if err := l.Allow("alice"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Allow(alice) = %v, a locked key must survive eviction", err)
+	}
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS products (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	description TEXT,
+	price NUMERIC NOT NULL,
+	stock BIGINT DEFAULT 0,
+	user_id BIGINT,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	CONSTRAINT fk_products_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
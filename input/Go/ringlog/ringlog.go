@@ -0,0 +1,74 @@
+// Package ringlog keeps the most recent entries of a log in a fixed-size ring.
+package ringlog
+
+// Log holds up to max entries, dropping the oldest once full. A max of zero
+// or less means the log is unbounded.
+type Log[T any] struct {
+	buf  []T
+	head int
+	n    int
+	max  int
+}
+
+// New returns an empty log that keeps at most max entries.
+func New[T any](max int) *Log[T] {
+	l := &Log[T]{}
+	l.SetMax(max)
+	return l
+}
+
+// Append adds v as the newest entry, overwriting the oldest if the log is full.
+func (l *Log[T]) Append(v T) {
+	if l.max <= 0 {
+		l.buf = append(l.buf, v)
+		l.n++
+		return
+	}
+
+	if l.n < l.max {
+		l.buf[(l.head+l.n)%l.max] = v
+		l.n++
+		return
+	}
+
+	l.buf[l.head] = v
+	l.head = (l.head + 1) % l.max
+}
+
+// Entries returns a copy of the entries, oldest first.
+func (l *Log[T]) Entries() []T {
+	entries := make([]T, l.n)
+	for i := range entries {
+		entries[i] = l.buf[(l.head+i)%len(l.buf)]
+	}
+	return entries
+}
+
+// Len returns the number of entries currently held.
+func (l *Log[T]) Len() int {
+	return l.n
+}
+
+// Max returns the current capacity, or a value <= 0 if the log is unbounded.
+func (l *Log[T]) Max() int {
+	return l.max
+}
+
+// SetMax changes the capacity. Shrinking keeps the newest entries; growing or
+// making the log unbounded keeps all of them.
+func (l *Log[T]) SetMax(max int) {
+	entries := l.Entries()
+	if max > 0 && len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+
+	l.max = max
+	l.head = 0
+	l.n = len(entries)
+	if max > 0 {
+		l.buf = make([]T, max)
+		copy(l.buf, entries)
+	} else {
+		l.buf = entries
+	}
+}
@@ -0,0 +1,91 @@
+package ringlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func appendAll(l *Log[int], n int) {
+	for i := 1; i <= n; i++ {
+		l.Append(i)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		max     int
+		appends int
+		want    []int
+	}{
+		{"empty", 3, 0, []int{}},
+		{"below max", 3, 2, []int{1, 2}},
+		{"at max", 3, 3, []int{1, 2, 3}},
+		{"wraps once", 3, 4, []int{2, 3, 4}},
+		{"wraps several times", 3, 10, []int{8, 9, 10}},
+		{"max of one", 1, 5, []int{5}},
+		{"zero max is unbounded", 0, 5, []int{1, 2, 3, 4, 5}},
+		{"negative max is unbounded", -1, 5, []int{1, 2, 3, 4, 5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := New[int](tc.max)
+			appendAll(l, tc.appends)
+
+			if got := l.Entries(); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Entries() = %v, want %v", got, tc.want)
+			}
+			if got := l.Len(); got != len(tc.want) {
+				t.Fatalf("Len() = %d, want %d", got, len(tc.want))
+			}
+		})
+	}
+}
+
+func TestSetMax(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		max     int
+		appends int
+		newMax  int
+		after   []int
+		want    []int
+	}{
+		{"shrink keeps newest", 5, 5, 2, nil, []int{4, 5}},
+		{"shrink after wraparound", 3, 7, 2, nil, []int{6, 7}},
+		{"shrink then append", 5, 5, 2, []int{6}, []int{5, 6}},
+		{"shrink below len of partial log", 5, 3, 2, nil, []int{2, 3}},
+		{"grow keeps all", 3, 5, 5, []int{6, 7}, []int{3, 4, 5, 6, 7}},
+		{"grow then wrap", 2, 3, 3, []int{4, 5}, []int{3, 4, 5}},
+		{"unbounded keeps all", 3, 5, 0, []int{6}, []int{3, 4, 5, 6}},
+		{"bounded from unbounded", 0, 5, 2, []int{6}, []int{5, 6}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := New[int](tc.max)
+			appendAll(l, tc.appends)
+			l.SetMax(tc.newMax)
+			for _, v := range tc.after {
+				l.Append(v)
+			}
+
+			if got := l.Entries(); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Entries() = %v, want %v", got, tc.want)
+			}
+			if got := l.Len(); got != len(tc.want) {
+				t.Fatalf("Len() = %d, want %d", got, len(tc.want))
+			}
+			if got := l.Max(); got != tc.newMax {
+				t.Fatalf("Max() = %d, want %d", got, tc.newMax)
+			}
+		})
+	}
+}
+
+func TestEntriesReturnsCopy(t *testing.T) {
+	l := New[int](3)
+	appendAll(l, 3)
+
+	l.Entries()[0] = 100
+	if got := l.Entries(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("Entries() = %v after modifying a previous result", got)
+	}
+}